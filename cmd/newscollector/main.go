@@ -0,0 +1,358 @@
+// command line for running collections and comparing their reports.
+//
+//	newscollector collect  -sources sources.yaml -out ./beans -report ./reports -pipeline pipeline.yaml
+//	newscollector schedule -sources sources.yaml -out ./beans -report ./reports -state schedule_state.json -metrics :9090
+//	newscollector refresh -sources sources.yaml -out ./beans -days 3
+//	newscollector diff ./reports/report-old.json ./reports/report-new.json
+//	newscollector replay ./archive > documents.json
+//	NEWSCOLLECTOR_API_KEYS=key1,key2 newscollector serve -addr :8080 -sources sources.yaml -out ./beans
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	ds "github.com/soumitsalman/beansack/sdk"
+	"github.com/soumitsalman/newscollector/collector"
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/metrics"
+	"github.com/soumitsalman/newscollector/server"
+)
+
+const _USAGE = `usage:
+  newscollector collect  [flags]            collect from every enabled source once and exit with 3 if a source is degraded (needs -health)
+  newscollector schedule [flags]            keep collecting from each source on its schedule
+  newscollector refresh [flags]             poll the comments and likes of the stories collected in the last few days and save the ones that changed
+  newscollector diff OLD_REPORT NEW_REPORT  compare two run reports and exit with 1 if a source regressed
+  newscollector replay ARCHIVE_DIR          re-extract every archived HTML page, PDF and text file offline and print the documents as JSON
+  newscollector serve    [flags]            serve the HTTP API. API keys are read from NEWSCOLLECTOR_API_KEYS (comma separated)`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, _USAGE)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "collect":
+		os.Exit(collect(os.Args[2:]))
+	case "schedule":
+		os.Exit(schedule(os.Args[2:]))
+	case "refresh":
+		os.Exit(refresh(os.Args[2:]))
+	case "diff":
+		os.Exit(diff(os.Args[2:]))
+	case "replay":
+		os.Exit(replay(os.Args[2:]))
+	case "serve":
+		os.Exit(serve(os.Args[2:]))
+	default:
+		fmt.Fprintln(os.Stderr, _USAGE)
+		os.Exit(2)
+	}
+}
+
+type collectFlags struct {
+	sources    string
+	out        string
+	report     string
+	health     string
+	archive    string
+	record     string
+	replay     string
+	engagement string
+	pipeline   string
+}
+
+func (flags *collectFlags) register(flag_set *flag.FlagSet) {
+	flag_set.StringVar(&flags.sources, "sources", "./sources.yaml", "YAML, JSON or CSV sources file")
+	flag_set.StringVar(&flags.out, "out", ".", "directory where the collected beans are saved as JSON")
+	flag_set.StringVar(&flags.report, "report", "", "directory where run reports are written. empty means no reports")
+	flag_set.StringVar(&flags.archive, "archive", "", "directory where every raw response is archived as WARC. empty means no archive")
+	flag_set.StringVar(&flags.record, "record", "", "directory where every response and redirect is recorded as fixtures for replaying the run later")
+	flag_set.StringVar(&flags.replay, "replay", "", "directory of a recorded run to reproduce without the network")
+	flag_set.StringVar(&flags.engagement, "engagement", "", "file where the stories with comments and likes are kept for refreshing, e.g. ./engagement_state.json. empty means no tracking")
+	flag_set.StringVar(&flags.pipeline, "pipeline", "", "YAML or JSON file with the pipeline stages and near-duplicate detection to run before storing. empty means none")
+	flag_set.StringVar(&flags.health, "health", "", "file where the extraction health history of each source is kept, e.g. ./source_health.json. empty means no health tracking")
+}
+
+func (flags *collectFlags) newCollector() collector.NewsSiteCollector {
+	return flags.newCollectorWithDays(0)
+}
+
+// days is how far back RefreshEngagement goes. 0 means the default
+func (flags *collectFlags) newCollectorWithDays(days int) collector.NewsSiteCollector {
+	site_collector := collector.NewCollector(flags.sources, fileStore(flags.out))
+	site_collector.NoiseStore = noiseStore(flags.out)
+	site_collector.ReportDir = flags.report
+	if flags.record != "" {
+		site_collector.RecordMode, site_collector.RecordDir = loaders.RECORD_MODE, flags.record
+	} else if flags.replay != "" {
+		site_collector.RecordMode, site_collector.RecordDir = loaders.REPLAY_MODE, flags.replay
+	}
+	if flags.archive != "" {
+		site_collector.Archive = loaders.NewWARCArchiver(flags.archive)
+	}
+	if flags.engagement != "" {
+		site_collector.Engagement = collector.NewEngagementTracker(&collector.EngagementConfig{Days: days, StateFile: flags.engagement})
+	}
+	if flags.health != "" {
+		site_collector.Health = collector.NewHealthTracker(&collector.HealthConfig{StateFile: flags.health})
+	}
+	if flags.pipeline != "" {
+		pipeline_config, err := collector.ReadPipelineConfig(flags.pipeline)
+		if err != nil {
+			log.Fatalln("FAILED reading pipeline", err)
+		}
+		site_collector.Pipeline = pipeline_config.NewPipeline()
+		if pipeline_config.Dedup != nil {
+			site_collector.Deduplicator = collector.NewDeduplicator(pipeline_config.Dedup)
+			site_collector.ClusterStore = clusterStore(flags.out)
+		}
+	}
+	return site_collector
+}
+
+func collect(args []string) int {
+	var flags collectFlags
+	flag_set := flag.NewFlagSet("collect", flag.ExitOnError)
+	flags.register(flag_set)
+	flag_set.Parse(args)
+
+	site_collector := flags.newCollector()
+	defer closeArchive(site_collector)
+	report := site_collector.Collect()
+	if degraded := report.DegradedSources(); len(degraded) > 0 {
+		fmt.Fprintln(os.Stderr, "degraded sources:", strings.Join(degraded, ", "))
+		return 3
+	}
+	return 0
+}
+
+func refresh(args []string) int {
+	var flags collectFlags
+	flag_set := flag.NewFlagSet("refresh", flag.ExitOnError)
+	flags.register(flag_set)
+	days := flag_set.Int("days", 3, "refresh the stories collected within this many days")
+	flag_set.Parse(args)
+	if flags.engagement == "" {
+		fmt.Fprintln(os.Stderr, "FAILED refreshing: -engagement has to be the state file that collect kept the stories in")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	count, err := flags.newCollectorWithDays(*days).RefreshEngagement(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Println(count, "stories updated")
+	return 0
+}
+
+func schedule(args []string) int {
+	var flags collectFlags
+	flag_set := flag.NewFlagSet("schedule", flag.ExitOnError)
+	flags.register(flag_set)
+	state := flag_set.String("state", "./schedule_state.json", "file where the last run of each source is kept")
+	metrics_addr := flag_set.String("metrics", "", "address to serve prometheus metrics on (e.g. :9090). empty means no metrics")
+	flag_set.Parse(args)
+
+	if *metrics_addr != "" {
+		go func() {
+			log.Println("FAILED serving metrics", metrics.Serve(*metrics_addr))
+		}()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	site_collector := flags.newCollector()
+	defer closeArchive(site_collector)
+	collector.NewScheduler(site_collector, *state).Run(ctx)
+	return 0
+}
+
+func replay(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, _USAGE)
+		return 2
+	}
+	docs, err := loaders.ExtractFromArchive(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FAILED reading archive", err)
+		return 1
+	}
+	data, _ := json.MarshalIndent(docs, "", "\t")
+	fmt.Println(string(data))
+	return 0
+}
+
+func serve(args []string) int {
+	var flags collectFlags
+	flag_set := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.register(flag_set)
+	addr := flag_set.String("addr", ":8080", "address to listen on")
+	timeout := flag_set.Duration("timeout", 30*time.Second, "how long an extract request can take")
+	max_concurrent := flag_set.Int("max-concurrent", 4, "max number of extractions and collections running at the same time")
+	no_auth := flag_set.Bool("insecure-no-auth", false, "serve without API keys when NEWSCOLLECTOR_API_KEYS is empty. only for trusted networks")
+	proxy := flag_set.String("proxy", "", "proxy for /extract (http://, https:// or socks5://)")
+	ca_bundle := flag_set.String("ca-bundle", "", "PEM file with extra CAs to trust for /extract")
+	flag_set.Parse(args)
+
+	// keys stay out of the flags so they don't show up in the process list
+	var api_keys []string
+	for _, key := range strings.Split(os.Getenv("NEWSCOLLECTOR_API_KEYS"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			api_keys = append(api_keys, key)
+		}
+	}
+	config := &server.ServerConfig{
+		APIKeys:       api_keys,
+		AllowNoAuth:   *no_auth,
+		Timeout:       *timeout,
+		MaxConcurrent: *max_concurrent,
+	}
+	if *proxy != "" || *ca_bundle != "" {
+		config.Loader = &loaders.WebLoaderConfig{HTTP: &loaders.HTTPConfig{Proxy: *proxy, CABundle: *ca_bundle}}
+	}
+	if len(api_keys) == 0 && !*no_auth {
+		fmt.Fprintln(os.Stderr, "FAILED serving: set NEWSCOLLECTOR_API_KEYS or pass -insecure-no-auth")
+		return 2
+	}
+	site_collector := flags.newCollector()
+	defer closeArchive(site_collector)
+	api_server := server.NewServer(site_collector, config)
+	log.Println("serving on", *addr)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- api_server.ListenAndServe(*addr)
+	}()
+	select {
+	case err := <-errs:
+		log.Println("FAILED serving", err)
+		return 1
+	case <-ctx.Done():
+		return 0
+	}
+}
+
+// flushes the last WARC file of a command that archived its responses
+func closeArchive(site_collector collector.NewsSiteCollector) {
+	if site_collector.Archive == nil {
+		return
+	}
+	if err := site_collector.Archive.Close(); err != nil {
+		log.Println("FAILED closing archive", err)
+	}
+}
+
+func diff(args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, _USAGE)
+		return 2
+	}
+	old_report, err := collector.ReadReport(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FAILED reading report", err)
+		return 2
+	}
+	new_report, err := collector.ReadReport(args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "FAILED reading report", err)
+		return 2
+	}
+
+	regressed := 0
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "SOURCE\tDISCOVERED\tFETCHED\tEMPTY\tERRORS\tSTORED\tREGRESSIONS")
+	for _, source_diff := range collector.DiffReports(old_report, new_report) {
+		old_source, new_source := source_diff.Old, source_diff.New
+		if old_source == nil {
+			old_source = &collector.SourceReport{}
+		}
+		if new_source == nil {
+			new_source = &collector.SourceReport{}
+		}
+		regressions := "-"
+		if len(source_diff.Regressions) > 0 {
+			regressed++
+			data, _ := json.Marshal(source_diff.Regressions)
+			regressions = string(data)
+		}
+		fmt.Fprintf(writer, "%s\t%d -> %d\t%d -> %d\t%d -> %d\t%d -> %d\t%d -> %d\t%s\n",
+			source_diff.Name,
+			old_source.Discovered, new_source.Discovered,
+			old_source.Fetched, new_source.Fetched,
+			old_source.EmptyBodies, new_source.EmptyBodies,
+			old_source.ErrorCount(), new_source.ErrorCount(),
+			old_source.Stored, new_source.Stored,
+			regressions)
+	}
+	writer.Flush()
+	if regressed > 0 {
+		fmt.Println(regressed, "source(s) regressed")
+		return 1
+	}
+	return 0
+}
+
+// saves the near-duplicate clusters of every batch as a JSON file in dir
+func clusterStore(dir string) func([]collector.ClusterMember) {
+	return func(members []collector.ClusterMember) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Println("FAILED creating output directory", err)
+			return
+		}
+		data, _ := json.MarshalIndent(members, "", "\t")
+		filename := fmt.Sprintf("clusters_%s.json", time.Now().Format("2006-01-02-15-04-05.000"))
+		if err := os.WriteFile(filepath.Join(dir, filename), data, 0644); err != nil {
+			log.Println("FAILED saving clusters", err)
+		}
+	}
+}
+
+// saves every batch of media noise as a JSON file in dir
+func noiseStore(dir string) func([]ds.MediaNoise) {
+	return func(noises []ds.MediaNoise) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Println("FAILED creating output directory", err)
+			return
+		}
+		data, _ := json.MarshalIndent(noises, "", "\t")
+		filename := fmt.Sprintf("engagement_%s.json", time.Now().Format("2006-01-02-15-04-05.000"))
+		if err := os.WriteFile(filepath.Join(dir, filename), data, 0644); err != nil {
+			log.Println("FAILED saving engagement", err)
+		}
+	}
+}
+
+var _FILE_NAME_REGEX = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// saves every batch of beans as a JSON file in dir
+func fileStore(dir string) func([]ds.Bean) {
+	return func(beans []ds.Bean) {
+		if len(beans) == 0 {
+			return
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Println("FAILED creating output directory", err)
+			return
+		}
+		data, _ := json.MarshalIndent(beans, "", "\t")
+		filename := fmt.Sprintf("%s_%s.json", _FILE_NAME_REGEX.ReplaceAllString(beans[0].Source, "_"), time.Now().Format("2006-01-02-15-04-05.000"))
+		if err := os.WriteFile(filepath.Join(dir, filename), data, 0644); err != nil {
+			log.Println("FAILED saving beans", err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ds "github.com/soumitsalman/beansack/sdk"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+var update = flag.Bool("update", false, "rewrite the bean golden files from the loader fixtures")
+
+// loads every loader case from its fixtures and compares the beans made from the documents against
+// testdata/beans so that changes to toBeans show up here
+func TestBeansAgainstGoldenFiles(t *testing.T) {
+	for _, test_case := range loadertest.Cases {
+		t.Run(test_case.Name, func(t *testing.T) {
+			docs, err := loadertest.LoadCase(filepath.Join("..", "loaders", "testdata"), test_case)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, _ := json.MarshalIndent(toBeans(docs), "", "\t")
+			beans_file := filepath.Join("testdata", "beans", test_case.Name+".json")
+			if *update {
+				if err := os.WriteFile(beans_file, actual, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			expected, err := os.ReadFile(beans_file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// round trip so that formatting differences in the file don't matter
+			var expected_beans []ds.Bean
+			json.Unmarshal(expected, &expected_beans)
+			expected, _ = json.MarshalIndent(expected_beans, "", "\t")
+			if string(expected) != string(actual) {
+				t.Errorf("beans differ from %s\n\twant: %s\n\tgot:  %s", beans_file, expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+const (
+	_DEFAULT_MAX_DISTANCE   = 3
+	_DEFAULT_RETENTION_DAYS = 7
+	_SHINGLE_SIZE           = 3
+	// anything shorter than this does not carry enough signal for a fingerprint
+	_MIN_FINGERPRINT_TOKENS = 20
+)
+
+type DedupConfig struct {
+	// max hamming distance between 2 fingerprints for them to be considered near-duplicates
+	MaxDistance int `json:"max_distance,omitempty" yaml:"max_distance,omitempty"`
+	// when true duplicates are removed from the output. when false they are kept and marked with the cluster id and canonical url
+	DropDuplicates bool `json:"drop_duplicates,omitempty" yaml:"drop_duplicates,omitempty"`
+	// file where fingerprints are persisted across runs. "" keeps the index in memory only
+	StateFile string `json:"state_file,omitempty" yaml:"state_file,omitempty"`
+	// fingerprints older than this are pruned from the index
+	RetentionDays int `json:"retention_days,omitempty" yaml:"retention_days,omitempty"`
+}
+
+// the near-duplicate cluster of a stored bean. beansack has no field for it so it goes to NewsSiteCollector.ClusterStore
+type ClusterMember struct {
+	Url       string `json:"url"`
+	ClusterId string `json:"cluster_id"`
+	// url of the original. "" for the original itself
+	CanonicalUrl string `json:"canonical_url,omitempty"`
+}
+
+func clusterMembers(docs []*loaders.Document) []ClusterMember {
+	members := make([]ClusterMember, 0, len(docs))
+	for _, doc := range docs {
+		if doc.ClusterId != "" {
+			members = append(members, ClusterMember{Url: doc.URL, ClusterId: doc.ClusterId, CanonicalUrl: doc.CanonicalURL})
+		}
+	}
+	return members
+}
+
+type fingerprintEntry struct {
+	Fingerprint uint64 `json:"fingerprint"`
+	URL         string `json:"url"`
+	ClusterId   string `json:"cluster_id"`
+	Collected   int64  `json:"collected"`
+}
+
+// //	NEAR-DUPLICATE DETECTOR		////
+// clusters near-duplicate documents across loaders and across runs using simhash over Document.Text
+// the first document seen in a cluster is the canonical original
+type Deduplicator struct {
+	Config  *DedupConfig
+	entries []fingerprintEntry
+	// sources can be collected concurrently by the scheduler
+	lock sync.Mutex
+}
+
+func NewDeduplicator(config *DedupConfig) *Deduplicator {
+	if config.MaxDistance <= 0 {
+		config.MaxDistance = _DEFAULT_MAX_DISTANCE
+	}
+	if config.RetentionDays <= 0 {
+		config.RetentionDays = _DEFAULT_RETENTION_DAYS
+	}
+	dedup := &Deduplicator{Config: config}
+	dedup.load()
+	return dedup
+}
+
+// assigns cluster ids to the documents and returns the documents that should be stored.
+// depending on the config the duplicates are either dropped or returned with CanonicalURL set to the original
+func (dedup *Deduplicator) Process(docs []*loaders.Document) []*loaders.Document {
+	dedup.lock.Lock()
+	defer dedup.lock.Unlock()
+	now := time.Now().Unix()
+	output := make([]*loaders.Document, 0, len(docs))
+	for _, doc := range docs {
+		fingerprint, ok := simhash(doc.Text)
+		if !ok {
+			// not enough text to say anything about it
+			output = append(output, doc)
+			continue
+		}
+		if original := dedup.findNearest(fingerprint, doc.URL); original != nil {
+			doc.ClusterId = original.ClusterId
+			if original.URL != doc.URL {
+				doc.CanonicalURL = original.URL
+				if dedup.Config.DropDuplicates {
+					continue
+				}
+			}
+		} else {
+			doc.ClusterId = fmt.Sprintf("%016x", fingerprint)
+			dedup.entries = append(dedup.entries, fingerprintEntry{
+				Fingerprint: fingerprint,
+				URL:         doc.URL,
+				ClusterId:   doc.ClusterId,
+				Collected:   now,
+			})
+		}
+		output = append(output, doc)
+	}
+	return output
+}
+
+// persists the fingerprint index so that the next run can detect duplicates of what was collected in this one
+func (dedup *Deduplicator) Save() error {
+	dedup.lock.Lock()
+	defer dedup.lock.Unlock()
+	if dedup.Config.StateFile == "" {
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -dedup.Config.RetentionDays).Unix()
+	entries := make([]fingerprintEntry, 0, len(dedup.entries))
+	for _, entry := range dedup.entries {
+		if entry.Collected >= cutoff {
+			entries = append(entries, entry)
+		}
+	}
+	dedup.entries = entries
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dedup.Config.StateFile, data, 0644)
+}
+
+func (dedup *Deduplicator) load() {
+	if dedup.Config.StateFile == "" {
+		return
+	}
+	if data, err := os.ReadFile(dedup.Config.StateFile); err == nil {
+		json.Unmarshal(data, &dedup.entries)
+	}
+}
+
+// returns the closest entry within MaxDistance. an exact URL match always wins so that re-collecting the same page does not turn it into its own duplicate
+func (dedup *Deduplicator) findNearest(fingerprint uint64, url string) *fingerprintEntry {
+	var nearest *fingerprintEntry
+	min_distance := dedup.Config.MaxDistance + 1
+	for i := range dedup.entries {
+		entry := &dedup.entries[i]
+		if entry.URL == url {
+			return entry
+		}
+		if distance := bits.OnesCount64(entry.Fingerprint ^ fingerprint); distance < min_distance {
+			nearest, min_distance = entry, distance
+		}
+	}
+	return nearest
+}
+
+var _TOKEN_REGEX = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// 64-bit simhash over word shingles. returns false if the text is too short to fingerprint
+func simhash(text string) (uint64, bool) {
+	tokens := _TOKEN_REGEX.FindAllString(strings.ToLower(text), -1)
+	if len(tokens) < _MIN_FINGERPRINT_TOKENS {
+		return 0, false
+	}
+	var weights [64]int
+	for i := 0; i+_SHINGLE_SIZE <= len(tokens); i++ {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(strings.Join(tokens[i:i+_SHINGLE_SIZE], " ")))
+		hash := hasher.Sum64()
+		for b := 0; b < 64; b++ {
+			if hash&(1<<b) != 0 {
+				weights[b]++
+			} else {
+				weights[b]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for b := 0; b < 64; b++ {
+		if weights[b] > 0 {
+			fingerprint |= 1 << b
+		}
+	}
+	return fingerprint, true
+}
@@ -0,0 +1,181 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	ds "github.com/soumitsalman/beansack/sdk"
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+const (
+	_DEFAULT_REFRESH_DAYS       = 3
+	_DEFAULT_ENGAGEMENT_TIMEOUT = 10 * time.Second
+)
+
+type EngagementConfig struct {
+	// stories collected within this many days are refreshed. older ones are forgotten
+	Days int
+	// file where the collected stories are persisted across runs. "" keeps them in memory only
+	StateFile string
+	// how the engagement URLs of sources without their own fetcher are fetched. nil means plain HTTP
+	Fetcher loaders.Fetcher
+	// how long polling a story can take. 0 means 10 seconds
+	Timeout time.Duration
+}
+
+type trackedStory struct {
+	URL           string `json:"url"`
+	Source        string `json:"source"`
+	EngagementURL string `json:"engagement_url"`
+	Collected     int64  `json:"collected"`
+	Comments      int    `json:"comments"`
+	Likes         int    `json:"likes"`
+	// name of the configured source the story was collected from. its HTTP settings are used for polling
+	SourceName string `json:"source_name,omitempty"`
+}
+
+// //	ENGAGEMENT REFRESH		////
+// remembers the stories that came with comments and likes (hacker news, reddit) and polls them again later so that
+// the feed sees the engagement going up. only the engagement is polled, the articles are never fetched again
+type EngagementTracker struct {
+	Config  *EngagementConfig
+	stories map[string]trackedStory
+	// sources can be collected concurrently by the scheduler
+	lock sync.Mutex
+}
+
+func NewEngagementTracker(config *EngagementConfig) *EngagementTracker {
+	if config.Days <= 0 {
+		config.Days = _DEFAULT_REFRESH_DAYS
+	}
+	if config.Fetcher == nil {
+		config.Fetcher = loaders.NewHTTPFetcher()
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = _DEFAULT_ENGAGEMENT_TIMEOUT
+	}
+	tracker := &EngagementTracker{Config: config, stories: make(map[string]trackedStory)}
+	tracker.load()
+	return tracker
+}
+
+// starts tracking the stored documents of a source that have somewhere to poll the engagement from
+func (tracker *EngagementTracker) Track(source_name string, docs []*loaders.Document) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	now := time.Now().Unix()
+	for _, doc := range docs {
+		if doc.EngagementURL == "" {
+			continue
+		}
+		if _, ok := tracker.stories[doc.URL]; ok {
+			continue
+		}
+		tracker.stories[doc.URL] = trackedStory{
+			URL:           doc.URL,
+			Source:        doc.Source,
+			SourceName:    source_name,
+			EngagementURL: doc.EngagementURL,
+			Collected:     now,
+			Comments:      doc.Comments,
+			Likes:         doc.Likes,
+		}
+	}
+}
+
+// polls every story collected in the last Config.Days and returns the media noise of the ones whose
+// comments or likes changed since they were last seen. stops early if ctx is cancelled.
+// fetchers has the fetcher of each source by name, e.g. with the proxy and headers of the source. the stories of
+// sources that aren't in it go through Config.Fetcher and the ones whose fetcher is nil are skipped
+func (tracker *EngagementTracker) Refresh(ctx context.Context, fetchers map[string]loaders.Fetcher) []ds.MediaNoise {
+	// the polling happens without the lock so that collecting isn't held up by it
+	due := tracker.dueStories()
+	noises := make([]ds.MediaNoise, 0)
+	for _, story := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		fetcher, ok := fetchers[story.SourceName]
+		if !ok {
+			fetcher = tracker.Config.Fetcher
+		} else if fetcher == nil {
+			continue
+		}
+		poll_ctx, cancel := context.WithTimeout(ctx, tracker.Config.Timeout)
+		engagement, err := loaders.FetchEngagement(poll_ctx, fetcher, story.EngagementURL)
+		cancel()
+		if err != nil {
+			slog.Warn("FAILED refreshing engagement", "source", story.Source, "url", story.URL, "error", err)
+			continue
+		}
+		if engagement.Comments == story.Comments && engagement.Likes == story.Likes {
+			continue
+		}
+		tracker.update(story.URL, engagement)
+		noises = append(noises, ds.MediaNoise{
+			BeanUrl:       story.URL,
+			Updated:       time.Now().Unix(),
+			Source:        story.Source,
+			ContentId:     engagement.ContentId,
+			Channel:       engagement.Channel,
+			ContainerUrl:  engagement.ContainerURL,
+			Comments:      engagement.Comments,
+			ThumbsupCount: engagement.Likes,
+			ThumbsupRatio: engagement.LikesRatio,
+		})
+	}
+	return noises
+}
+
+// the stories within Config.Days. forgets the older ones
+func (tracker *EngagementTracker) dueStories() []trackedStory {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	cutoff := time.Now().AddDate(0, 0, -tracker.Config.Days).Unix()
+	due := make([]trackedStory, 0, len(tracker.stories))
+	for url, story := range tracker.stories {
+		if story.Collected < cutoff {
+			delete(tracker.stories, url)
+		} else {
+			due = append(due, story)
+		}
+	}
+	return due
+}
+
+func (tracker *EngagementTracker) update(url string, engagement *loaders.Engagement) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	if story, ok := tracker.stories[url]; ok {
+		story.Comments, story.Likes = engagement.Comments, engagement.Likes
+		tracker.stories[url] = story
+	}
+}
+
+// persists the tracked stories so that the next run can refresh them
+func (tracker *EngagementTracker) Save() error {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	if tracker.Config.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(tracker.stories)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tracker.Config.StateFile, data, 0644)
+}
+
+func (tracker *EngagementTracker) load() {
+	if tracker.Config.StateFile == "" {
+		return
+	}
+	if data, err := os.ReadFile(tracker.Config.StateFile); err == nil {
+		json.Unmarshal(data, &tracker.stories)
+	}
+}
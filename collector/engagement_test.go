@@ -0,0 +1,49 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ds "github.com/soumitsalman/beansack/sdk"
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+func TestRefreshUsesSourceHTTPSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "newscollector-test" {
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `[{"data": {"children": [{"kind": "t3", "data": {"name": "t3_1d4x2yz", "subreddit": "programming",
+			"permalink": "/r/programming/comments/1d4x2yz/a_post/", "num_comments": 12, "score": 40, "upvote_ratio": 0.9}}]}}]`)
+	}))
+	defer server.Close()
+
+	var noises []ds.MediaNoise
+	site_collector := NewCollectorWithSources([]Source{{
+		Name: "reddit",
+		Type: JSON_SOURCE,
+		HTTP: &loaders.HTTPConfig{Headers: map[string]string{"User-Agent": "newscollector-test"}},
+	}}, nil)
+	site_collector.NoiseStore = func(batch []ds.MediaNoise) { noises = batch }
+	site_collector.Engagement = NewEngagementTracker(&EngagementConfig{Timeout: time.Second})
+	site_collector.Engagement.Track("reddit", []*loaders.Document{{
+		URL:           "https://example.com/post",
+		Source:        "Reddit",
+		EngagementURL: server.URL + "/r/programming/comments/1d4x2yz/a_post/.json",
+		Comments:      3,
+		Likes:         10,
+	}})
+
+	count, err := site_collector.RefreshEngagement(context.Background())
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 story to be updated, got %d: %v", count, err)
+	}
+	if noises[0].Comments != 12 || noises[0].ThumbsupCount != 40 || noises[0].ContentId != "t3_1d4x2yz" {
+		t.Errorf("expected the refreshed engagement, got %+v", noises[0])
+	}
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_HEALTH_WINDOW      = 10
+	_DEFAULT_MIN_HEALTH_HISTORY = 3
+	_DEFAULT_MIN_LENGTH_RATIO   = 0.5
+	_DEFAULT_MAX_EMPTY_INCREASE = 0.3
+)
+
+type HealthConfig struct {
+	// number of past healthy runs the rolling averages are taken over
+	Window int
+	// a source is only judged once it has this many healthy runs behind it
+	MinHistory int
+	// degraded if the average text length falls below this fraction of the rolling average
+	MinLengthRatio float64
+	// degraded if the share of empty bodies goes up by this much over the rolling average
+	MaxEmptyIncrease float64
+	// file where the history is persisted across runs. "" keeps it in memory only
+	StateFile string
+}
+
+// one run of a source as far as extraction health goes
+type HealthSample struct {
+	Time          time.Time `json:"time"`
+	AvgTextLength float64   `json:"avg_text_length"`
+	EmptyRatio    float64   `json:"empty_ratio"`
+}
+
+// //	EXTRACTION HEALTH TRACKER		////
+// catches sources whose extraction silently broke, e.g. after a redesign the body selector stops matching and
+// pages come back empty or with only a fraction of the text. each run is compared against the rolling averages
+// of the source's previous healthy runs. degraded runs are kept out of the history so that a broken source
+// stays flagged until it recovers instead of becoming the new normal
+type HealthTracker struct {
+	Config  *HealthConfig
+	history map[string][]HealthSample
+	// sources can be collected concurrently by the scheduler
+	lock sync.Mutex
+}
+
+func NewHealthTracker(config *HealthConfig) *HealthTracker {
+	if config.Window <= 0 {
+		config.Window = _DEFAULT_HEALTH_WINDOW
+	}
+	if config.MinHistory <= 0 {
+		config.MinHistory = _DEFAULT_MIN_HEALTH_HISTORY
+	}
+	if config.MinLengthRatio <= 0 {
+		config.MinLengthRatio = _DEFAULT_MIN_LENGTH_RATIO
+	}
+	if config.MaxEmptyIncrease <= 0 {
+		config.MaxEmptyIncrease = _DEFAULT_MAX_EMPTY_INCREASE
+	}
+	tracker := &HealthTracker{Config: config, history: make(map[string][]HealthSample)}
+	tracker.load()
+	return tracker
+}
+
+// compares the sample against the source's history and returns why it is degraded (nil if it is healthy).
+// healthy samples are added to the history
+func (tracker *HealthTracker) Check(source string, sample HealthSample) []string {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	history := tracker.history[source]
+	var reasons []string
+	if len(history) >= tracker.Config.MinHistory {
+		avg_length, avg_empty := 0.0, 0.0
+		for _, past := range history {
+			avg_length += past.AvgTextLength
+			avg_empty += past.EmptyRatio
+		}
+		avg_length /= float64(len(history))
+		avg_empty /= float64(len(history))
+		if sample.AvgTextLength < avg_length*tracker.Config.MinLengthRatio {
+			reasons = append(reasons, fmt.Sprintf("average text length dropped to %.0f from %.0f", sample.AvgTextLength, avg_length))
+		}
+		if sample.EmptyRatio-avg_empty >= tracker.Config.MaxEmptyIncrease {
+			reasons = append(reasons, fmt.Sprintf("empty bodies went up to %.0f%% from %.0f%%", sample.EmptyRatio*100, avg_empty*100))
+		}
+	}
+	if len(reasons) == 0 {
+		history = append(history, sample)
+		if len(history) > tracker.Config.Window {
+			history = history[len(history)-tracker.Config.Window:]
+		}
+		tracker.history[source] = history
+	}
+	return reasons
+}
+
+// persists the history so that the next run is judged against this one
+func (tracker *HealthTracker) Save() error {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+	if tracker.Config.StateFile == "" {
+		return nil
+	}
+	data, err := json.Marshal(tracker.history)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(tracker.Config.StateFile, data, 0644)
+}
+
+func (tracker *HealthTracker) load() {
+	if tracker.Config.StateFile == "" {
+		return
+	}
+	if data, err := os.ReadFile(tracker.Config.StateFile); err == nil {
+		json.Unmarshal(data, &tracker.history)
+	}
+}
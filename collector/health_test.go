@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHealthTrackerCheck(t *testing.T) {
+	healthy := HealthSample{AvgTextLength: 2000, EmptyRatio: 0.1}
+	tests := []struct {
+		name string
+		runs []HealthSample
+		// the expected reason of each run, "" for healthy
+		want []string
+	}{
+		{
+			// too little history to judge
+			name: "new source",
+			runs: []HealthSample{healthy, {AvgTextLength: 10, EmptyRatio: 1}, healthy},
+			want: []string{"", "", ""},
+		},
+		{
+			name: "text length dropped",
+			runs: []HealthSample{healthy, healthy, healthy, {AvgTextLength: 900, EmptyRatio: 0.1}},
+			want: []string{"", "", "", "average text length dropped to 900 from 2000"},
+		},
+		{
+			name: "text length dropped by half",
+			runs: []HealthSample{healthy, healthy, healthy, {AvgTextLength: 1000, EmptyRatio: 0.1}},
+			want: []string{"", "", "", ""},
+		},
+		{
+			name: "empty bodies went up",
+			runs: []HealthSample{healthy, healthy, healthy, {AvgTextLength: 2000, EmptyRatio: 0.4}},
+			want: []string{"", "", "", "empty bodies went up to 40% from 10%"},
+		},
+		{
+			// degraded runs stay out of the history so the source stays degraded until it recovers
+			name: "degraded and recovered",
+			runs: []HealthSample{healthy, healthy, healthy, {AvgTextLength: 100, EmptyRatio: 0.9}, {AvgTextLength: 100, EmptyRatio: 0.9}, healthy, {AvgTextLength: 1500, EmptyRatio: 0.2}},
+			want: []string{"", "", "", "average text length dropped", "average text length dropped", "", ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := NewHealthTracker(&HealthConfig{})
+			for i, run := range test.runs {
+				reasons := tracker.Check("hackaday", run)
+				switch {
+				case test.want[i] == "" && len(reasons) > 0:
+					t.Errorf("run %d: expected healthy, got %q", i+1, reasons)
+				case test.want[i] != "" && (len(reasons) == 0 || !strings.HasPrefix(reasons[0], test.want[i])):
+					t.Errorf("run %d: expected %q, got %q", i+1, test.want[i], reasons)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthTrackerKeepsHistoryAcrossRuns(t *testing.T) {
+	state_file := filepath.Join(t.TempDir(), "source_health.json")
+	tracker := NewHealthTracker(&HealthConfig{StateFile: state_file})
+	for i := 0; i < 3; i++ {
+		tracker.Check("hackaday", HealthSample{AvgTextLength: 2000})
+	}
+	if err := tracker.Save(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewHealthTracker(&HealthConfig{StateFile: state_file})
+	if reasons := reloaded.Check("hackaday", HealthSample{AvgTextLength: 100}); len(reasons) == 0 {
+		t.Error("expected the reloaded history to flag the drop")
+	}
+	// another source's history doesn't count
+	if reasons := reloaded.Check("techspot", HealthSample{AvgTextLength: 100}); len(reasons) > 0 {
+		t.Errorf("expected no history for another source, got %q", reasons)
+	}
+}
@@ -0,0 +1,129 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+// a stage in the document pipeline. returning nil drops the document.
+// returning an error keeps the document that went into the stage and moves on to the next stage
+type Processor interface {
+	Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error)
+}
+
+// lets a plain function be used as a Processor
+type ProcessorFunc func(ctx context.Context, doc *loaders.Document) (*loaders.Document, error)
+
+func (f ProcessorFunc) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	return f(ctx, doc)
+}
+
+type stage struct {
+	name      string
+	processor Processor
+}
+
+// //	DOCUMENT PIPELINE		////
+// ordered stages that every loaded document goes through before it is stored
+type Pipeline struct {
+	stages []stage
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// appends a stage. the name shows up in the logs
+func (pipeline *Pipeline) Add(name string, processor Processor) *Pipeline {
+	pipeline.stages = append(pipeline.stages, stage{name: name, processor: processor})
+	return pipeline
+}
+
+// runs the documents through every stage in order and logs how many each stage dropped or failed on
+func (pipeline *Pipeline) Run(ctx context.Context, source string, docs []*loaders.Document) []*loaders.Document {
+	for _, stage := range pipeline.stages {
+		output := make([]*loaders.Document, 0, len(docs))
+		failed := 0
+		for _, doc := range docs {
+			processed, err := stage.processor.Process(ctx, doc)
+			if err != nil {
+				failed++
+				output = append(output, doc)
+			} else if processed != nil {
+				output = append(output, processed)
+			}
+		}
+		slog.Info("pipeline stage", "stage", stage.name, "source", source, "in", len(docs), "dropped", len(docs)-len(output), "failed", failed)
+		docs = output
+	}
+	return docs
+}
+
+// //	PIPELINE CONFIG		////
+// turns the built-in stages and the near-duplicate detection on from a YAML or JSON file, e.g. for the command line.
+// the stages run in the order of the fields below and the ones that are left out are skipped
+type PipelineConfig struct {
+	// FieldNormalizer
+	Normalize bool `json:"normalize,omitempty" yaml:"normalize,omitempty"`
+	// DomainBlocklist
+	BlockedDomains []string `json:"blocked_domains,omitempty" yaml:"blocked_domains,omitempty"`
+	// MinLengthFilter
+	MinWords int `json:"min_words,omitempty" yaml:"min_words,omitempty"`
+	// LanguageFilter
+	Languages           []string `json:"languages,omitempty" yaml:"languages,omitempty"`
+	DropUnknownLanguage bool     `json:"drop_unknown_language,omitempty" yaml:"drop_unknown_language,omitempty"`
+	// KeywordFilter
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	// KeywordExtractor. MaxKeywords 0 means no limit
+	ExtractKeywords bool `json:"extract_keywords,omitempty" yaml:"extract_keywords,omitempty"`
+	MaxKeywords     int  `json:"max_keywords,omitempty" yaml:"max_keywords,omitempty"`
+	// Summarizer. 0 means no summaries
+	SummarySentences int `json:"summary_sentences,omitempty" yaml:"summary_sentences,omitempty"`
+	// near-duplicate detection after the stages. nil means off
+	Dedup *DedupConfig `json:"dedup,omitempty" yaml:"dedup,omitempty"`
+}
+
+func ReadPipelineConfig(path string) (*PipelineConfig, error) {
+	var config PipelineConfig
+	if err := readConfigFile(path, &config); err != nil {
+		return nil, err
+	}
+	if config.MinWords < 0 || config.MaxKeywords < 0 || config.SummarySentences < 0 {
+		return nil, fmt.Errorf("%s: min_words, max_keywords and summary_sentences can't be negative", path)
+	}
+	return &config, nil
+}
+
+// the pipeline of the configured stages. nil if none are configured
+func (config *PipelineConfig) NewPipeline() *Pipeline {
+	pipeline := NewPipeline()
+	if config.Normalize {
+		pipeline.Add("normalize", FieldNormalizer{})
+	}
+	if len(config.BlockedDomains) > 0 {
+		pipeline.Add("blocklist", DomainBlocklist{Domains: config.BlockedDomains})
+	}
+	if config.MinWords > 0 {
+		pipeline.Add("min-length", MinLengthFilter{MinWords: config.MinWords})
+	}
+	if len(config.Languages) > 0 {
+		pipeline.Add("language", LanguageFilter{Languages: config.Languages, DropUnknown: config.DropUnknownLanguage})
+	}
+	if len(config.Include) > 0 || len(config.Exclude) > 0 {
+		pipeline.Add("keyword-filter", KeywordFilter{Include: config.Include, Exclude: config.Exclude})
+	}
+	if config.ExtractKeywords {
+		pipeline.Add("keywords", KeywordExtractor{MaxCount: config.MaxKeywords})
+	}
+	if config.SummarySentences > 0 {
+		pipeline.Add("summary", Summarizer{Sentences: config.SummarySentences})
+	}
+	if len(pipeline.stages) == 0 {
+		return nil
+	}
+	return pipeline
+}
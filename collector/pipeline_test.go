@@ -0,0 +1,44 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+func TestReadPipelineConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	os.WriteFile(path, []byte("normalize: true\nmin_words: 100\nsummary_sentences: 3\ndedup:\n  drop_duplicates: true\n"), 0644)
+	config, err := ReadPipelineConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, stage := range config.NewPipeline().stages {
+		names = append(names, stage.name)
+	}
+	if len(names) != 3 || names[0] != "normalize" || names[1] != "min-length" || names[2] != "summary" {
+		t.Errorf("stages = %v, want [normalize min-length summary]", names)
+	}
+	if config.Dedup == nil || !config.Dedup.DropDuplicates {
+		t.Errorf("dedup = %+v, want drop_duplicates", config.Dedup)
+	}
+
+	os.WriteFile(path, []byte("min_word: 100\n"), 0644)
+	if _, err := ReadPipelineConfig(path); err == nil {
+		t.Error("unknown setting was accepted")
+	}
+}
+
+func TestSummarizerDefaultsSentences(t *testing.T) {
+	doc := &loaders.Document{Text: strings.Repeat("The council approved a new budget for the northern bus routes today. ", 5) +
+		"Residents have asked for better bus connections for many years now."}
+	doc, err := Summarizer{Sentences: -1}.Process(context.Background(), doc)
+	if err != nil || doc.Summary == "" {
+		t.Errorf("Summarizer{Sentences: -1} summary = %q, err = %v, want a summary", doc.Summary, err)
+	}
+}
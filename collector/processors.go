@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/nlp"
+)
+
+// //	BUILT-IN PIPELINE STAGES		////
+
+// keeps documents that mention at least one of Include (if any) and none of Exclude.
+// matches whole words in the title, text and keywords regardless of case
+type KeywordFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (filter KeywordFilter) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	words := make(map[string]bool)
+	for _, word := range nlp.Words(doc.Title + " " + doc.Text + " " + strings.Join(doc.Keywords, " ")) {
+		words[word] = true
+	}
+	mentions := func(keyword string) bool {
+		// multi word keywords need every word to be present
+		keyword_words := nlp.Words(keyword)
+		return len(keyword_words) > 0 && !slices.ContainsFunc(keyword_words, func(word string) bool { return !words[word] })
+	}
+	if slices.ContainsFunc(filter.Exclude, mentions) {
+		return nil, nil
+	}
+	if len(filter.Include) > 0 && !slices.ContainsFunc(filter.Include, mentions) {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+// drops documents with fewer than MinWords words in the text
+type MinLengthFilter struct {
+	MinWords int
+}
+
+func (filter MinLengthFilter) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	if len(strings.Fields(doc.Text)) < filter.MinWords {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+// drops documents from any of the domains or their subdomains
+type DomainBlocklist struct {
+	Domains []string
+}
+
+func (blocklist DomainBlocklist) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	doc_url, err := url.Parse(doc.URL)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(doc_url.Hostname())
+	for _, domain := range blocklist.Domains {
+		domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return nil, nil
+		}
+	}
+	return doc, nil
+}
+
+// keeps documents in one of the languages (ISO 639-1 codes such as "en").
+// documents whose language can't be detected are kept unless DropUnknown is set
+type LanguageFilter struct {
+	Languages   []string
+	DropUnknown bool
+}
+
+func (filter LanguageFilter) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	lang := nlp.DetectLanguage(doc.Title + "\n" + doc.Text)
+	if lang == nlp.UNKNOWN_LANGUAGE {
+		if filter.DropUnknown {
+			return nil, nil
+		}
+		return doc, nil
+	}
+	if !slices.Contains(filter.Languages, lang) {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+var (
+	_SPACES_REGEX      = regexp.MustCompile(`[ \t\x{00a0}]+`)
+	_BLANK_LINES_REGEX = regexp.MustCompile(`\n\s*\n+`)
+	// query parameters that only exist for tracking and make the same article look like different URLs
+	_TRACKING_PARAMS = []string{"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "fbclid", "gclid", "mc_cid", "mc_eid", "ref", "cmpid"}
+)
+
+// cleans up the fields: collapses whitespace, strips tracking parameters and fragments from the URL
+// and lower cases and de-duplicates the keywords
+type FieldNormalizer struct{}
+
+func (normalizer FieldNormalizer) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	doc.Title = strings.TrimSpace(_SPACES_REGEX.ReplaceAllString(doc.Title, " "))
+	// documents made outside the loaders may only have the string
+	if len(doc.Authors) > 0 {
+		doc.SetAuthors(doc.Authors...)
+	} else {
+		doc.SetAuthors(loaders.ParseAuthors(doc.Author)...)
+	}
+	doc.Source = strings.TrimSpace(doc.Source)
+	doc.Text = strings.TrimSpace(_BLANK_LINES_REGEX.ReplaceAllString(_SPACES_REGEX.ReplaceAllString(doc.Text, " "), "\n\n"))
+	if doc_url, err := url.Parse(strings.TrimSpace(doc.URL)); err == nil {
+		// re-encoding sorts the parameters so leave the query alone unless something has to go
+		query := doc_url.Query()
+		if slices.ContainsFunc(_TRACKING_PARAMS, query.Has) {
+			for _, param := range _TRACKING_PARAMS {
+				query.Del(param)
+			}
+			doc_url.RawQuery = query.Encode()
+		}
+		doc_url.Fragment = ""
+		doc.URL = doc_url.String()
+	}
+	keywords := make([]string, 0, len(doc.Keywords))
+	for _, keyword := range doc.Keywords {
+		keyword = strings.ToLower(strings.TrimSpace(_SPACES_REGEX.ReplaceAllString(keyword, " ")))
+		if keyword != "" && !slices.Contains(keywords, keyword) {
+			keywords = append(keywords, keyword)
+		}
+	}
+	doc.Keywords = keywords
+	return doc, nil
+}
+
+// fills in the keywords from the title and text when the source didn't provide any, otherwise normalizes
+// and de-duplicates the ones it did. keeps at most MaxCount keywords (<= 0 means no limit)
+type KeywordExtractor struct {
+	MaxCount int
+}
+
+func (extractor KeywordExtractor) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	if len(doc.Keywords) == 0 {
+		doc.Keywords = nlp.ExtractKeywords(doc.Title+".\n"+doc.Text, extractor.MaxCount)
+	} else {
+		doc.Keywords = nlp.NormalizeKeywords(doc.Keywords, extractor.MaxCount)
+	}
+	return doc, nil
+}
+
+const _DEFAULT_SUMMARY_SENTENCES = 3
+
+// adds an extractive summary of up to Sentences sentences to documents that don't have one
+type Summarizer struct {
+	// 0 means 3
+	Sentences int
+}
+
+func (summarizer Summarizer) Process(ctx context.Context, doc *loaders.Document) (*loaders.Document, error) {
+	if summarizer.Sentences <= 0 {
+		summarizer.Sentences = _DEFAULT_SUMMARY_SENTENCES
+	}
+	if doc.Summary == "" {
+		doc.Summary = nlp.Summarize(doc.Text, summarizer.Sentences)
+	}
+	return doc, nil
+}
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+const (
+	// a source regressed if it stored less than this fraction of what it stored before
+	_STORED_REGRESSION_RATIO = 0.5
+	// or if its share of empty bodies went up by this much
+	_EMPTY_REGRESSION_INCREASE = 0.25
+	// or if its errors more than doubled and went up by at least this many
+	_ERROR_REGRESSION_INCREASE = 5
+)
+
+// //	RUN REPORT		////
+// what happened during a collection run
+type RunReport struct {
+	StartTime time.Time      `json:"start_time"`
+	EndTime   time.Time      `json:"end_time"`
+	Sources   []SourceReport `json:"sources"`
+}
+
+// what happened to one source during a run. duplicates skipped are AlreadyLoaded + NearDuplicates
+type SourceReport struct {
+	Name      string    `json:"name"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// discovered, filtered by date, already loaded, fetched, empty bodies and errors from the loader
+	loaders.LoaderStats
+	LowQualitySkipped int `json:"low_quality_skipped"`
+	PipelineDropped   int `json:"pipeline_dropped"`
+	NearDuplicates    int `json:"near_duplicates"`
+	// beans handed to the store function
+	Stored int `json:"stored"`
+	// average length of the non-empty bodies
+	AvgTextLength float64 `json:"avg_text_length"`
+	// set when the extraction got noticeably worse than the source's history. see HealthTracker
+	Degraded     bool     `json:"degraded"`
+	HealthIssues []string `json:"health_issues,omitempty"`
+}
+
+// names of the sources flagged as degraded
+func (report *RunReport) DegradedSources() []string {
+	var names []string
+	for _, source := range report.Sources {
+		if source.Degraded {
+			names = append(names, source.Name)
+		}
+	}
+	return names
+}
+
+// failed requests of every class
+func (report SourceReport) ErrorCount() int {
+	count := 0
+	for _, errors := range report.Errors {
+		count += errors
+	}
+	return count
+}
+
+func (report SourceReport) emptyRatio() float64 {
+	if report.Fetched == 0 {
+		return 0
+	}
+	return float64(report.EmptyBodies) / float64(report.Fetched)
+}
+
+func ReadReport(path string) (*RunReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report RunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// writes the report as report-<start time>.json in dir and returns the path
+func WriteReport(report *RunReport, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "report-"+report.StartTime.Format("2006-01-02T15-04-05.000")+".json")
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// //	REPORT DIFF		////
+// how a source changed between two reports
+type SourceDiff struct {
+	Name string
+	// nil if the source is not in that report
+	Old *SourceReport
+	New *SourceReport
+	// empty unless the source got noticeably worse
+	Regressions []string
+}
+
+// compares every source in either report. sources are in the order of the new report followed by the ones only in the old one
+func DiffReports(old_report, new_report *RunReport) []SourceDiff {
+	old_sources := make(map[string]*SourceReport)
+	for i := range old_report.Sources {
+		old_sources[old_report.Sources[i].Name] = &old_report.Sources[i]
+	}
+	var diffs []SourceDiff
+	for i := range new_report.Sources {
+		new_source := &new_report.Sources[i]
+		old_source := old_sources[new_source.Name]
+		delete(old_sources, new_source.Name)
+		diffs = append(diffs, SourceDiff{Name: new_source.Name, Old: old_source, New: new_source, Regressions: regressions(old_source, new_source)})
+	}
+	for i := range old_report.Sources {
+		if old_source, ok := old_sources[old_report.Sources[i].Name]; ok {
+			diffs = append(diffs, SourceDiff{Name: old_source.Name, Old: old_source, Regressions: []string{"missing from the new report"}})
+		}
+	}
+	return diffs
+}
+
+func regressions(old_source, new_source *SourceReport) []string {
+	var found []string
+	if new_source.Degraded {
+		found = append(found, "degraded: "+strings.Join(new_source.HealthIssues, ", "))
+	}
+	if old_source == nil {
+		return found
+	}
+	if old_source.Stored > 0 && float64(new_source.Stored) < float64(old_source.Stored)*_STORED_REGRESSION_RATIO {
+		found = append(found, fmt.Sprintf("stored went from %d to %d", old_source.Stored, new_source.Stored))
+	}
+	if new_source.emptyRatio()-old_source.emptyRatio() >= _EMPTY_REGRESSION_INCREASE {
+		found = append(found, fmt.Sprintf("empty bodies went from %.0f%% to %.0f%%", old_source.emptyRatio()*100, new_source.emptyRatio()*100))
+	}
+	if old_errors, new_errors := old_source.ErrorCount(), new_source.ErrorCount(); new_errors > 2*old_errors && new_errors-old_errors >= _ERROR_REGRESSION_INCREASE {
+		found = append(found, fmt.Sprintf("errors went from %d to %d", old_errors, new_errors))
+	}
+	return found
+}
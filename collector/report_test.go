@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+func sourceReport(name string, stored, fetched, empty, errors int) SourceReport {
+	report := SourceReport{Name: name, Stored: stored}
+	report.Fetched = fetched
+	report.EmptyBodies = empty
+	if errors > 0 {
+		report.Errors = map[string]int{loaders.ERROR_TIMEOUT: errors}
+	}
+	return report
+}
+
+func TestRegressions(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new SourceReport
+		want     []string
+	}{
+		{"unchanged", sourceReport("hackaday", 20, 20, 1, 1), sourceReport("hackaday", 20, 20, 1, 1), nil},
+		{"stored half", sourceReport("hackaday", 20, 20, 0, 0), sourceReport("hackaday", 10, 20, 0, 0), nil},
+		{"stored less than half", sourceReport("hackaday", 20, 20, 0, 0), sourceReport("hackaday", 9, 20, 0, 0), []string{"stored went from 20 to 9"}},
+		{"nothing stored before", sourceReport("hackaday", 0, 0, 0, 0), sourceReport("hackaday", 0, 20, 0, 0), nil},
+		{"empty bodies up by less", sourceReport("hackaday", 20, 20, 0, 0), sourceReport("hackaday", 20, 20, 4, 0), nil},
+		{"empty bodies up by a quarter", sourceReport("hackaday", 20, 20, 0, 0), sourceReport("hackaday", 20, 20, 5, 0), []string{"empty bodies went from 0% to 25%"}},
+		{"errors doubled but few", sourceReport("hackaday", 20, 20, 0, 2), sourceReport("hackaday", 20, 20, 0, 6), nil},
+		{"errors not doubled", sourceReport("hackaday", 20, 20, 0, 10), sourceReport("hackaday", 20, 20, 0, 18), nil},
+		{"errors more than doubled", sourceReport("hackaday", 20, 20, 0, 2), sourceReport("hackaday", 20, 20, 0, 7), []string{"errors went from 2 to 7"}},
+		{
+			"everything at once",
+			sourceReport("hackaday", 20, 20, 0, 0),
+			sourceReport("hackaday", 2, 20, 10, 8),
+			[]string{"stored went from 20 to 2", "empty bodies went from 0% to 50%", "errors went from 0 to 8"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := regressions(&test.old, &test.new); !slices.Equal(got, test.want) {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	degraded := sourceReport("techspot", 20, 20, 0, 0)
+	degraded.Degraded, degraded.HealthIssues = true, []string{"average text length dropped to 200 from 2000"}
+	old_report := &RunReport{Sources: []SourceReport{
+		sourceReport("hackaday", 20, 20, 0, 0),
+		sourceReport("wired", 10, 10, 0, 0),
+		sourceReport("techspot", 20, 20, 0, 0),
+	}}
+	new_report := &RunReport{Sources: []SourceReport{
+		sourceReport("hackaday", 5, 20, 0, 0),
+		degraded,
+		sourceReport("arxiv", 30, 0, 0, 0),
+	}}
+
+	diffs := DiffReports(old_report, new_report)
+	tests := []struct {
+		name          string
+		has_old       bool
+		has_new       bool
+		first_problem string
+	}{
+		{"hackaday", true, true, "stored went from 20 to 5"},
+		{"techspot", true, true, "degraded: average text length dropped"},
+		// added sources have nothing to regress from
+		{"arxiv", false, true, ""},
+		{"wired", true, false, "missing from the new report"},
+	}
+	if len(diffs) != len(tests) {
+		t.Fatalf("expected %d diffs, got %+v", len(tests), diffs)
+	}
+	for i, test := range tests {
+		diff := diffs[i]
+		if diff.Name != test.name || (diff.Old != nil) != test.has_old || (diff.New != nil) != test.has_new {
+			t.Errorf("diff %d: expected %s with old %v and new %v, got %+v", i, test.name, test.has_old, test.has_new, diff)
+			continue
+		}
+		switch {
+		case test.first_problem == "" && len(diff.Regressions) > 0:
+			t.Errorf("%s: expected no regressions, got %q", diff.Name, diff.Regressions)
+		case test.first_problem != "" && (len(diff.Regressions) == 0 || !strings.HasPrefix(diff.Regressions[0], test.first_problem)):
+			t.Errorf("%s: expected %q, got %q", diff.Name, test.first_problem, diff.Regressions)
+		}
+	}
+}
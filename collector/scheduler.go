@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	_DEFAULT_SCHEDULE = "@daily"
+	_DEFAULT_JITTER   = time.Minute
+)
+
+// //	RECURRING COLLECTION		////
+// runs every enabled source on its own schedule until the context is cancelled.
+// each source runs in its own loop so a slow source never overlaps with itself or holds up the others.
+// the last run of each source is persisted so that a restart only runs what is due.
+// since different sources run concurrently the store function has to be safe to call from multiple goroutines
+type Scheduler struct {
+	collector NewsSiteCollector
+	// file where the last run times are kept. "" keeps them in memory only
+	StateFile string
+	// random delay of up to this much is added to every run so that sources sharing a schedule don't all fire at once
+	Jitter time.Duration
+	// used for sources that don't have a schedule
+	DefaultSchedule string
+
+	last_runs map[string]time.Time
+	lock      sync.Mutex
+	// the clock and the collection. the tests swap them out
+	now     func() time.Time
+	after   func(time.Duration) <-chan time.Time
+	collect func(ctx context.Context, source Source) SourceReport
+}
+
+func NewScheduler(collector NewsSiteCollector, state_file string) *Scheduler {
+	scheduler := &Scheduler{
+		collector:       collector,
+		StateFile:       state_file,
+		Jitter:          _DEFAULT_JITTER,
+		DefaultSchedule: _DEFAULT_SCHEDULE,
+		last_runs:       make(map[string]time.Time),
+		now:             time.Now,
+		after:           time.After,
+	}
+	scheduler.collect = collector.collectSingleSource
+	if data, err := os.ReadFile(state_file); err == nil {
+		json.Unmarshal(data, &scheduler.last_runs)
+	}
+	return scheduler
+}
+
+// blocks until ctx is cancelled
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, source := range scheduler.collector.sources {
+		if !source.IsEnabled() {
+			continue
+		}
+		spec := source.Schedule
+		if spec == "" {
+			spec = scheduler.DefaultSchedule
+		}
+		schedule, err := cron.ParseStandard(spec)
+		if err != nil {
+			slog.Error("FAILED scheduling", "source", source.Name, "error", err)
+			continue
+		}
+		wg.Add(1)
+		go func(source Source, schedule cron.Schedule) {
+			defer wg.Done()
+			scheduler.runSource(ctx, source, schedule)
+		}(source, schedule)
+	}
+	wg.Wait()
+}
+
+func (scheduler *Scheduler) runSource(ctx context.Context, source Source, schedule cron.Schedule) {
+	for {
+		wait := scheduler.nextWait(source.Name, schedule)
+		slog.Info("next collection", "source", source.Name, "wait", wait.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return
+		case <-scheduler.after(wait):
+		}
+
+		start_time := scheduler.now()
+		scheduler.collect(ctx, source)
+		scheduler.setLastRun(source.Name, start_time)
+		slog.Info("collection finished", "source", source.Name, "duration", scheduler.now().Sub(start_time))
+	}
+}
+
+func (scheduler *Scheduler) nextWait(name string, schedule cron.Schedule) time.Duration {
+	// sources that never ran or missed their slot while the process was down are due right away
+	wait := time.Duration(0)
+	if last_run := scheduler.lastRun(name); !last_run.IsZero() {
+		wait = schedule.Next(last_run).Sub(scheduler.now())
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if scheduler.Jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(scheduler.Jitter)))
+	}
+	return wait
+}
+
+func (scheduler *Scheduler) lastRun(name string) time.Time {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	return scheduler.last_runs[name]
+}
+
+func (scheduler *Scheduler) setLastRun(name string, run_time time.Time) {
+	scheduler.lock.Lock()
+	defer scheduler.lock.Unlock()
+	scheduler.last_runs[name] = run_time
+	if scheduler.StateFile == "" {
+		return
+	}
+	if data, err := json.MarshalIndent(scheduler.last_runs, "", "\t"); err == nil {
+		if err := os.WriteFile(scheduler.StateFile, data, 0644); err != nil {
+			slog.Error("FAILED saving scheduler state", "error", err)
+		}
+	}
+}
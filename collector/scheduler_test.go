@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestSchedulerResumesFromStateFile(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	state_file := filepath.Join(t.TempDir(), "schedule_state.json")
+	data, _ := json.Marshal(map[string]time.Time{
+		"hackaday": now.Add(-20 * time.Minute),
+		"techspot": now.Add(-3 * time.Hour),
+	})
+	if err := os.WriteFile(state_file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	scheduler := NewScheduler(NewsSiteCollector{}, state_file)
+	scheduler.Jitter = 0
+	scheduler.now = func() time.Time { return now }
+	hourly, _ := cron.ParseStandard("@every 1h")
+
+	tests := []struct {
+		source string
+		want   time.Duration
+	}{
+		{"hackaday", 40 * time.Minute},
+		// missed its slot while the process was down
+		{"techspot", 0},
+		{"never-ran", 0},
+	}
+	for _, test := range tests {
+		if wait := scheduler.nextWait(test.source, hourly); wait != test.want {
+			t.Errorf("%s: expected to wait %s, got %s", test.source, test.want, wait)
+		}
+	}
+}
+
+func TestSchedulerAddsJitter(t *testing.T) {
+	scheduler := NewScheduler(NewsSiteCollector{}, "")
+	scheduler.Jitter = time.Minute
+	hourly, _ := cron.ParseStandard("@every 1h")
+	waits := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		wait := scheduler.nextWait("hackaday", hourly)
+		if wait < 0 || wait >= time.Minute {
+			t.Fatalf("expected a wait within the jitter, got %s", wait)
+		}
+		waits[wait] = true
+	}
+	if len(waits) == 1 {
+		t.Error("expected the jitter to vary between runs")
+	}
+}
+
+func TestSchedulerNeverOverlapsASource(t *testing.T) {
+	sources := []Source{
+		{Name: "hackaday", Type: SITEMAP_SOURCE, Schedule: "@every 1m"},
+		{Name: "techspot", Type: SITEMAP_SOURCE, Schedule: "@every 1m"},
+		{Name: "medium", Type: MEDIUM_SOURCE, Enabled: new(bool)},
+	}
+	state_file := filepath.Join(t.TempDir(), "schedule_state.json")
+	scheduler := NewScheduler(NewsSiteCollector{sources: sources}, state_file)
+	scheduler.Jitter = 0
+	// every run is due right away so a source would overlap with itself if the scheduler let it
+	scheduler.after = func(time.Duration) <-chan time.Time {
+		fired := make(chan time.Time, 1)
+		fired <- time.Now()
+		return fired
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var lock sync.Mutex
+	running, runs := make(map[string]int), make(map[string]int)
+	scheduler.collect = func(ctx context.Context, source Source) SourceReport {
+		lock.Lock()
+		running[source.Name]++
+		runs[source.Name]++
+		if running[source.Name] > 1 {
+			t.Errorf("%s ran while its previous run was still going", source.Name)
+		}
+		if runs["hackaday"] >= 3 && runs["techspot"] >= 3 {
+			cancel()
+		}
+		lock.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+		lock.Lock()
+		running[source.Name]--
+		lock.Unlock()
+		return SourceReport{}
+	}
+	scheduler.Run(ctx)
+
+	if runs["medium"] != 0 {
+		t.Errorf("expected the disabled source not to run, got %d runs", runs["medium"])
+	}
+	var last_runs map[string]time.Time
+	data, _ := os.ReadFile(state_file)
+	if err := json.Unmarshal(data, &last_runs); err != nil || last_runs["hackaday"].IsZero() || last_runs["techspot"].IsZero() {
+		t.Errorf("expected the last runs in the state file, got %s", data)
+	}
+}
@@ -1,54 +1,335 @@
 package collector
 
 import (
+	"context"
 	"encoding/csv"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	ds "github.com/soumitsalman/beansack/sdk"
 	datautils "github.com/soumitsalman/data-utils"
 	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/metrics"
 )
 
+// what to do with documents that are not classified as loaders.QUALITY_OK
+const (
+	KEEP_LOW_QUALITY = iota
+	SKIP_LOW_QUALITY
+	// refetch the page once with the generic readability loader and skip it if it is still low quality
+	RETRY_LOW_QUALITY
+)
+
+const _RETRY_TIMEOUT = 30 * time.Second
+
 type NewsSiteCollector struct {
-	site_loaders []*loaders.WebLoader
-	store_func   func([]ds.Bean)
+	sources    []Source
+	store_func func([]ds.Bean)
+	// optional near-duplicate detection across all the loaders. nil means every document is stored
+	Deduplicator *Deduplicator
+	// optional. receives the near-duplicate cluster of every stored bean that has one, right after the beans are stored
+	ClusterStore func([]ClusterMember)
+	// one of KEEP_LOW_QUALITY, SKIP_LOW_QUALITY, RETRY_LOW_QUALITY
+	LowQualityAction int
+	// optional stages for filtering, enriching and transforming the documents before they are stored
+	Pipeline *Pipeline
+	// directory where a JSON report of every run is written. "" means no reports
+	ReportDir string
+	// optional extraction health tracking. degraded sources are flagged in the run report
+	Health *HealthTracker
+	// optional WARC archive of every raw response from every source
+	Archive *loaders.WARCArchiver
+	// loaders.LIVE_MODE, loaders.RECORD_MODE or loaders.REPLAY_MODE. each source is recorded in its own directory under RecordDir
+	RecordMode int
+	RecordDir  string
+	// optional tracking of the stories that have comments and likes so that RefreshEngagement can poll them again
+	Engagement *EngagementTracker
+	// where RefreshEngagement sends the updated media noise. required for refreshing
+	NoiseStore func([]ds.MediaNoise)
+}
+
+// sources can be a YAML, JSON or the older sitemaps CSV file. exits if the sources are invalid
+func NewCollector(sources string, store_func func([]ds.Bean)) NewsSiteCollector {
+	source_list, err := ReadSources(sources)
+	if err != nil {
+		log.Fatalln("FAILED reading sources", err)
+	}
+	return NewCollectorWithSources(source_list, store_func)
 }
 
-func NewCollector(sitemaps string, store_func func([]ds.Bean)) NewsSiteCollector {
+func NewCollectorWithSources(sources []Source, store_func func([]ds.Bean)) NewsSiteCollector {
 	return NewsSiteCollector{
-		site_loaders: createSiteLoaders(sitemaps),
-		store_func:   store_func,
+		sources:    sources,
+		store_func: store_func,
+	}
+}
+
+// collects from every enabled source once and returns what happened
+func (collector NewsSiteCollector) Collect() *RunReport {
+	report := &RunReport{StartTime: time.Now()}
+	for _, source := range collector.sources {
+		if source.IsEnabled() {
+			report.Sources = append(report.Sources, collector.collectSource(context.Background(), source))
+		}
+	}
+	report.EndTime = time.Now()
+	collector.saveState()
+	collector.saveReport(report)
+	return report
+}
+
+// collects from one source by name, whether it is enabled or not
+func (collector NewsSiteCollector) CollectSource(ctx context.Context, name string) (SourceReport, error) {
+	index := slices.IndexFunc(collector.sources, func(source Source) bool { return source.Name == name })
+	if index < 0 {
+		return SourceReport{}, fmt.Errorf("unknown source %q", name)
+	}
+	return collector.collectSingleSource(ctx, collector.sources[index]), nil
+}
+
+// runs that cover one source at a time save the state and get their own report right away
+func (collector NewsSiteCollector) collectSingleSource(ctx context.Context, source Source) SourceReport {
+	report := collector.collectSource(ctx, source)
+	collector.saveState()
+	collector.saveReport(&RunReport{StartTime: report.StartTime, EndTime: report.EndTime, Sources: []SourceReport{report}})
+	return report
+}
+
+// polls the comments and likes of the recently collected stories again and sends the ones that changed to NoiseStore.
+// nothing else is fetched or stored. returns how many were updated
+func (collector NewsSiteCollector) RefreshEngagement(ctx context.Context) (int, error) {
+	if collector.Engagement == nil || collector.NoiseStore == nil {
+		return 0, fmt.Errorf("refreshing engagement needs both Engagement and NoiseStore")
+	}
+	start_time := time.Now()
+	noises := collector.Engagement.Refresh(ctx, collector.engagementFetchers())
+	if len(noises) > 0 {
+		collector.NoiseStore(noises)
+	}
+	collector.saveState()
+	slog.Info("engagement refreshed", "count", len(noises), "duration", time.Since(start_time))
+	return len(noises), nil
+}
+
+// fetchers built from the HTTP settings of the sources that have them, so that refreshing goes through the same proxy
+// and headers as collecting. a source whose settings can't be applied gets nil so that its stories are skipped
+func (collector NewsSiteCollector) engagementFetchers() map[string]loaders.Fetcher {
+	fetchers := make(map[string]loaders.Fetcher)
+	for _, source := range collector.sources {
+		if source.HTTP == nil {
+			continue
+		}
+		fetcher, err := loaders.NewHTTPFetcherWithConfig(source.HTTP)
+		if err != nil {
+			slog.Error("FAILED configuring HTTP", "source", source.Name, "error", err)
+			fetchers[source.Name] = nil
+			continue
+		}
+		fetchers[source.Name] = fetcher
+	}
+	return fetchers
+}
+
+// the collector wide settings of the loaders of a source
+func (collector NewsSiteCollector) customizeLoader(source Source) func(config *loaders.WebLoaderConfig) {
+	return func(config *loaders.WebLoaderConfig) {
+		config.Archive = collector.Archive
+		config.Mode = collector.RecordMode
+		config.RecordDir = filepath.Join(collector.RecordDir, loaders.FixtureName(source.Name))
+	}
+}
+
+// the configured sources including the disabled ones
+func (collector NewsSiteCollector) Sources() []Source {
+	return slices.Clone(collector.sources)
+}
+
+func (collector NewsSiteCollector) collectSource(ctx context.Context, source Source) SourceReport {
+	report := SourceReport{Name: source.Name, StartTime: time.Now()}
+	loader := source.newLoader(collector.customizeLoader(source))
+	docs := loader.LoadSite()
+	report.LoaderStats = loader.Stats()
+	slog.Info("loaded", "source", source.Name, "count", len(docs), "duration", time.Since(report.StartTime))
+	collector.checkHealth(&report, docs)
+	datautils.ForEach(docs, func(doc **loaders.Document) {
+		(*doc).Category = source.Category
+		// every document gets its own copy so that appending to one doesn't change the others
+		(*doc).Tags = slices.Clone(source.Tags)
+	})
+	if collector.LowQualityAction != KEEP_LOW_QUALITY {
+		good_docs := collector.filterLowQuality(source, docs)
+		report.LowQualitySkipped = len(docs) - len(good_docs)
+		slog.Info("low quality skipped", "source", source.Name, "count", len(docs)-len(good_docs))
+		docs = good_docs
+	}
+	if collector.Pipeline != nil {
+		processed_docs := collector.Pipeline.Run(ctx, source.Name, docs)
+		report.PipelineDropped = len(docs) - len(processed_docs)
+		docs = processed_docs
+	}
+	if collector.Deduplicator != nil {
+		unique_docs := collector.Deduplicator.Process(docs)
+		report.NearDuplicates = len(docs) - len(unique_docs)
+		slog.Info("near-duplicates dropped", "source", source.Name, "count", len(docs)-len(unique_docs))
+		docs = unique_docs
+	}
+	// storeNewBeans(docs)
+	beans := toBeans(docs)
+	collector.store_func(beans)
+	if collector.ClusterStore != nil {
+		if members := clusterMembers(docs); len(members) > 0 {
+			collector.ClusterStore(members)
+		}
+	}
+	if collector.Engagement != nil {
+		collector.Engagement.Track(source.Name, docs)
+	}
+	report.Stored = len(beans)
+	report.EndTime = time.Now()
+	metrics.Stored.WithLabelValues(source.Name).Add(float64(len(beans)))
+	slog.Info("stored", "source", source.Name, "count", len(beans), "duration", report.EndTime.Sub(report.StartTime))
+	return report
+}
+
+// fills in the average text length and, if health is being tracked, whether the source is degraded
+func (collector NewsSiteCollector) checkHealth(report *SourceReport, docs []*loaders.Document) {
+	total_length, with_text := 0, 0
+	for _, doc := range docs {
+		if doc.Text != "" {
+			total_length += len(doc.Text)
+			with_text++
+		}
+	}
+	if with_text > 0 {
+		report.AvgTextLength = float64(total_length) / float64(with_text)
+	}
+	// nothing was fetched so there is nothing to judge the extraction by
+	if collector.Health == nil || report.Fetched == 0 {
+		return
+	}
+	report.HealthIssues = collector.Health.Check(report.Name, HealthSample{
+		Time:          report.StartTime,
+		AvgTextLength: report.AvgTextLength,
+		EmptyRatio:    report.emptyRatio(),
+	})
+	report.Degraded = len(report.HealthIssues) > 0
+	if report.Degraded {
+		slog.Warn("source degraded", "source", report.Name, "issues", report.HealthIssues)
 	}
 }
 
-func (collector NewsSiteCollector) Collect() {
-	for _, loader := range collector.site_loaders {
-		docs := loader.LoadSite()
-		log.Println(len(docs), "new beans found from", loader.Config.Sitemap)
-		// storeNewBeans(docs)
-		collector.store_func(toBeans(docs))
+// persists whatever needs to carry over to the next run
+func (collector NewsSiteCollector) saveState() {
+	if collector.Deduplicator != nil {
+		if err := collector.Deduplicator.Save(); err != nil {
+			slog.Error("FAILED saving fingerprints", "error", err)
+		}
+	}
+	if collector.Health != nil {
+		if err := collector.Health.Save(); err != nil {
+			slog.Error("FAILED saving source health", "error", err)
+		}
+	}
+	if collector.Engagement != nil {
+		if err := collector.Engagement.Save(); err != nil {
+			slog.Error("FAILED saving tracked stories", "error", err)
+		}
 	}
 }
 
-func readSitemapsCSV(sitemaps string) [][]string {
+func (collector NewsSiteCollector) saveReport(report *RunReport) {
+	if collector.ReportDir == "" {
+		return
+	}
+	if path, err := WriteReport(report, collector.ReportDir); err != nil {
+		slog.Error("FAILED saving run report", "error", err)
+	} else {
+		slog.Info("run report saved", "path", path)
+	}
+}
+
+func (collector NewsSiteCollector) filterLowQuality(source Source, docs []*loaders.Document) []*loaders.Document {
+	var retry_loader *loaders.WebLoader
+	return datautils.Filter(docs, func(doc **loaders.Document) bool {
+		if (*doc).Kind != "" && (*doc).Kind != loaders.ARTICLE {
+			// podcasts, videos, images, releases and papers are kept for what they are even with little or no text
+			return true
+		}
+		if (*doc).Quality == "" {
+			// the body was never fetched so there is no html to go by
+			(*doc).Quality = loaders.ClassifyQuality((*doc).Text, nil)
+		}
+		if (*doc).Quality == loaders.QUALITY_OK {
+			return true
+		}
+		if collector.LowQualityAction != RETRY_LOW_QUALITY {
+			return false
+		}
+		if retry_loader == nil {
+			// the same HTTP settings, archive and recording as the first pass, only with more time
+			config := source.loaderConfig(collector.customizeLoader(source))
+			config.Timeout = _RETRY_TIMEOUT
+			retry_loader = loaders.NewDefaultWebTextLoader(config)
+		}
+		// keep the metadata from the source and only take the body from the retry
+		if retried := retry_loader.LoadDocument((*doc).URL); retried != nil && retried.Quality == loaders.QUALITY_OK {
+			(*doc).Text = retried.Text
+			(*doc).Quality = retried.Quality
+			return true
+		}
+		return false
+	})
+}
+
+// each row is keyed by the column names in the header.
+// besides sitemap the optional columns are proxy, headers, cookies, ca_bundle and http2
+func readSitemapsCSV(sitemaps string) []map[string]string {
 	file, _ := os.Open(sitemaps)
 	defer file.Close()
-	items, _ := csv.NewReader(file).ReadAll()
-	// ignore the header
-	return items[1:]
+	reader := csv.NewReader(file)
+	// older files don't have the optional columns
+	reader.FieldsPerRecord = -1
+	items, _ := reader.ReadAll()
+	if len(items) == 0 {
+		return nil
+	}
+	header := datautils.Transform(items[0], func(name *string) string { return strings.ToLower(strings.TrimSpace(*name)) })
+	// the sitemap was always the first column whatever the header called it
+	if !slices.Contains(header, "sitemap") {
+		header[0] = "sitemap"
+	}
+	return datautils.Transform(items[1:], func(item *[]string) map[string]string {
+		row := make(map[string]string, len(header))
+		for i, value := range *item {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		return row
+	})
 }
 
-func createSiteLoaders(sitemaps string) []*loaders.WebLoader {
-	site_loaders := datautils.Transform(readSitemapsCSV(sitemaps), func(item *[]string) *loaders.WebLoader {
-		return loaders.NewDefaultNewsSitemapLoader(2, (*item)[0])
-	})
-	return append(site_loaders,
-		// this is a specialied loader
-		loaders.NewYCHackerNewsSiteLoader(),
-	)
+// nil if the row has no HTTP settings
+func readHTTPConfig(row map[string]string) *loaders.HTTPConfig {
+	if row["proxy"] == "" && row["headers"] == "" && row["cookies"] == "" && row["ca_bundle"] == "" && row["http2"] == "" {
+		return nil
+	}
+	return &loaders.HTTPConfig{
+		Proxy:        row["proxy"],
+		Headers:      loaders.ParseKeyValuePairs(row["headers"]),
+		Cookies:      loaders.ParseKeyValuePairs(row["cookies"]),
+		CABundle:     row["ca_bundle"],
+		DisableHTTP2: strings.EqualFold(row["http2"], "false"),
+	}
 }
 
+// converts loaded documents into beans for the beansack store
 func toBeans(docs []*loaders.Document) []ds.Bean {
 	beans := make([]ds.Bean, len(docs))
 	for i, doc := range docs {
@@ -56,10 +337,16 @@ func toBeans(docs []*loaders.Document) []ds.Bean {
 		beans[i].Source = doc.Source
 		beans[i].Title = doc.Title
 		beans[i].Kind = doc.Kind
-		beans[i].Text = doc.Text
+		beans[i].Text = beanText(doc)
+		beans[i].Summary = doc.Summary
+		// the joined string is what the beans have always had
 		beans[i].Author = doc.Author
+		if len(doc.Authors) > 0 {
+			beans[i].Author = loaders.JoinAuthors(doc.Authors)
+		}
 		beans[i].Created = doc.PublishDate
-		beans[i].Keywords = doc.Keywords
+		beans[i].Keywords = appendUnique(doc.Keywords, doc.Tags...)
+		beans[i].Topic = doc.Category
 		if doc.Comments > 0 || doc.Likes > 0 {
 			beans[i].MediaNoise = &ds.MediaNoise{
 				BeanUrl:       doc.URL,
@@ -71,3 +358,28 @@ func toBeans(docs []*loaders.Document) []ds.Bean {
 	}
 	return beans
 }
+
+// media documents rarely have more text than a description so the enclosure is spelled out for the feed to link to
+func beanText(doc *loaders.Document) string {
+	if doc.Media == nil || doc.Media.URL == "" {
+		return doc.Text
+	}
+	text := doc.Text
+	if text == "" {
+		text = doc.Media.Description
+	}
+	enclosure := fmt.Sprintf("%s: %s", doc.Kind, doc.Media.URL)
+	if doc.Media.Duration > 0 {
+		enclosure += fmt.Sprintf(" (%s)", time.Duration(doc.Media.Duration)*time.Second)
+	}
+	return strings.TrimSpace(text + "\n\n" + enclosure)
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
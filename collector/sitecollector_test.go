@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+func TestClusterStoreGetsDuplicateCluster(t *testing.T) {
+	text := "Officials said on Tuesday that the new bridge across the river will open next spring after three years of construction. " +
+		"The project ran over budget by twelve million dollars because of delays in steel deliveries and a wet winter. " +
+		"Commuters are expected to save twenty minutes a day once the old ferry route is retired. "
+	docs := []*loaders.Document{
+		{URL: "https://apnews.com/article/fox", Text: text},
+		{URL: "https://news.example.com/fox", Text: text},
+	}
+	docs = NewDeduplicator(&DedupConfig{}).Process(docs)
+	members := clusterMembers(docs)
+	if len(members) != 2 {
+		t.Fatalf("expected both documents to be kept, got %d", len(members))
+	}
+	if members[0].ClusterId == "" || members[1].ClusterId != members[0].ClusterId {
+		t.Errorf("expected the same cluster, got %q and %q", members[0].ClusterId, members[1].ClusterId)
+	}
+	if members[0].CanonicalUrl != "" || members[1].CanonicalUrl != docs[0].URL {
+		t.Errorf("expected the duplicate to point to %s, got %q", docs[0].URL, members[1].CanonicalUrl)
+	}
+}
+
+func TestRetryLowQualityUsesTheSourceConfig(t *testing.T) {
+	// the retry replays the recording of the source, so it only finds the article if it is loaded with the source's config
+	source := Source{Name: "City Desk", URL: "https://citydesk.example.com/sitemap.xml"}
+	url := "https://citydesk.example.com/night-buses"
+	record_dir := t.TempDir()
+	fixtures_dir := filepath.Join(record_dir, loaders.FixtureName(source.Name))
+	if err := os.MkdirAll(fixtures_dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(fixtures_dir, loaders.FixtureName(url)+".html"), []byte(loadertest.ArticlePage()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	site_collector := NewsSiteCollector{LowQualityAction: RETRY_LOW_QUALITY, RecordMode: loaders.REPLAY_MODE, RecordDir: record_dir}
+	docs := site_collector.filterLowQuality(source, []*loaders.Document{{
+		URL:     url,
+		Kind:    loaders.ARTICLE,
+		Text:    "The city council voted on Monday to extend the night bus service.",
+		Quality: loaders.QUALITY_TRUNCATED,
+	}})
+	if len(docs) != 1 {
+		t.Fatalf("expected the retried document to be kept, got %d documents", len(docs))
+	}
+	if docs[0].Quality != loaders.QUALITY_OK || !strings.Contains(docs[0].Text, loadertest.ARTICLE_PHRASE) {
+		t.Errorf("expected the full text from the recording, got %q: %q", docs[0].Quality, docs[0].Text)
+	}
+}
@@ -0,0 +1,326 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"github.com/soumitsalman/newscollector/loaders"
+	"gopkg.in/yaml.v3"
+)
+
+// source types
+const (
+	SITEMAP_SOURCE    = "sitemap"
+	HACKERNEWS_SOURCE = "hackernews"
+	MEDIUM_SOURCE     = "medium"
+	FEED_SOURCE       = "feed"
+	JSON_SOURCE       = "json"
+	GITHUB_SOURCE     = "github"
+	ARXIV_SOURCE      = "arxiv"
+)
+
+const _DEFAULT_DAYS = 2
+
+var (
+	_REPO_REGEX           = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+	_ARXIV_CATEGORY_REGEX = regexp.MustCompile(`^[a-z-]+(\.[A-Za-z-]+)?$`)
+)
+
+// overrides for how the content is extracted from the pages of a source
+type ExtractionRules struct {
+	// css selector for the element that holds the article body
+	BodySelector string `json:"body_selector,omitempty" yaml:"body_selector,omitempty"`
+	// regular expressions for URLs that should not be visited
+	DisallowedURLs []string `json:"disallowed_urls,omitempty" yaml:"disallowed_urls,omitempty"`
+	// follow the next page links of articles split across pages and stitch up to this many pages together
+	MaxPages int `json:"max_pages,omitempty" yaml:"max_pages,omitempty"`
+}
+
+type Source struct {
+	// unique name of the source. defaults to the host of the URL or the type
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// one of SITEMAP_SOURCE, FEED_SOURCE, JSON_SOURCE, GITHUB_SOURCE, ARXIV_SOURCE, HACKERNEWS_SOURCE, MEDIUM_SOURCE
+	Type string `json:"type" yaml:"type"`
+	// sitemap URL for SITEMAP_SOURCE, RSS or Atom feed URL for FEED_SOURCE, JSON listing for JSON_SOURCE (defaults to the preset's). optional base URL override for the others
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// how far back to collect. defaults to 2. hacker news always takes the current top stories
+	Days int `json:"days,omitempty" yaml:"days,omitempty"`
+	// cron expression, @hourly/@daily style descriptor or @every <duration> for the scheduler
+	Schedule string           `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Rules    *ExtractionRules `json:"rules,omitempty" yaml:"rules,omitempty"`
+	// attached to every document from this source
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Category string   `json:"category,omitempty" yaml:"category,omitempty"`
+	// nil means enabled
+	Enabled *bool               `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	HTTP    *loaders.HTTPConfig `json:"http,omitempty" yaml:"http,omitempty"`
+	// where the fields are in the listing of a JSON_SOURCE. either one of loaders.JSON_PRESETS or a mapping of its own
+	Preset  string               `json:"preset,omitempty" yaml:"preset,omitempty"`
+	Mapping *loaders.JSONMapping `json:"mapping,omitempty" yaml:"mapping,omitempty"`
+	// "owner/name" of the repos whose releases a GITHUB_SOURCE follows
+	Repos []string `json:"repos,omitempty" yaml:"repos,omitempty"`
+	// arXiv categories (e.g. cs.AI) an ARXIV_SOURCE follows
+	Categories []string `json:"categories,omitempty" yaml:"categories,omitempty"`
+}
+
+type sourcesFile struct {
+	Sources []Source `json:"sources" yaml:"sources"`
+}
+
+// reads and validates the sources from a YAML (.yaml, .yml), JSON (.json) or the older sitemaps CSV (.csv) file
+func ReadSources(path string) ([]Source, error) {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readSourcesCSV(path)
+	}
+	var file sourcesFile
+	if err := readConfigFile(path, &file); err != nil {
+		return nil, err
+	}
+	if err := validateSources(file.Sources); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file.Sources, nil
+}
+
+// decodes a YAML (.yaml, .yml) or JSON (.json) file into config. unknown fields are errors so that typos don't go unnoticed
+func readConfigFile(path string, config any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(config)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	default:
+		return fmt.Errorf("%s: unsupported file format. use .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// every row of the CSV is a sitemap source collected for 2 days and named after its URL. hacker news was always collected alongside them.
+// the older files were never checked for repeated sitemaps, so those are collected once instead of failing the whole file
+func readSourcesCSV(path string) ([]Source, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	sources := make([]Source, 0)
+	seen := make(map[string]bool)
+	for _, row := range readSitemapsCSV(path) {
+		sitemap := row["sitemap"]
+		if sitemap == "" || seen[sitemap] {
+			continue
+		}
+		seen[sitemap] = true
+		sources = append(sources, Source{
+			Name: sitemap,
+			Type: SITEMAP_SOURCE,
+			URL:  sitemap,
+			HTTP: readHTTPConfig(row),
+		})
+	}
+	sources = append(sources, Source{Type: HACKERNEWS_SOURCE})
+	if err := validateSources(sources); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sources, nil
+}
+
+// fills in the defaults and reports every problem found instead of stopping at the first one
+func validateSources(sources []Source) error {
+	var errs []error
+	names := make(map[string]int)
+	for i := range sources {
+		source := &sources[i]
+		source.Type = strings.ToLower(strings.TrimSpace(source.Type))
+		if preset, ok := loaders.JSON_PRESETS[source.Preset]; ok && source.Type == JSON_SOURCE && source.URL == "" {
+			source.URL = preset.Listing
+		}
+		if source.Name == "" {
+			source.Name = defaultSourceName(source)
+		}
+		if source.Days == 0 {
+			source.Days = _DEFAULT_DAYS
+		}
+		for _, err := range source.validate() {
+			errs = append(errs, fmt.Errorf("source %d (%s): %w", i+1, source.Name, err))
+		}
+		if first, ok := names[source.Name]; ok {
+			errs = append(errs, fmt.Errorf("source %d (%s): name is already used by source %d", i+1, source.Name, first))
+		} else {
+			names[source.Name] = i + 1
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (source *Source) validate() []error {
+	var errs []error
+	switch source.Type {
+	case SITEMAP_SOURCE, FEED_SOURCE:
+		if source.URL == "" {
+			errs = append(errs, fmt.Errorf("url is required for %s sources", source.Type))
+		}
+	case JSON_SOURCE:
+		switch _, ok := loaders.JSON_PRESETS[source.Preset]; {
+		case source.Preset != "" && source.Mapping != nil:
+			errs = append(errs, errors.New("preset and mapping can't be used together"))
+		case source.Preset != "" && !ok:
+			errs = append(errs, fmt.Errorf("unknown preset %q", source.Preset))
+		case source.Preset == "" && source.Mapping == nil:
+			errs = append(errs, errors.New("preset or mapping is required for json sources"))
+		case source.Mapping != nil:
+			if err := source.Mapping.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("mapping: %w", err))
+			}
+			if source.URL == "" && source.Mapping.Listing == "" {
+				errs = append(errs, errors.New("url is required for json sources"))
+			}
+		}
+	case GITHUB_SOURCE:
+		if len(source.Repos) == 0 {
+			errs = append(errs, errors.New("repos are required for github sources"))
+		}
+		for _, repo := range source.Repos {
+			if !_REPO_REGEX.MatchString(repo) {
+				errs = append(errs, fmt.Errorf("repo %q is not owner/name", repo))
+			}
+		}
+	case ARXIV_SOURCE:
+		if len(source.Categories) == 0 {
+			errs = append(errs, errors.New("categories are required for arxiv sources"))
+		}
+		for _, category := range source.Categories {
+			if !_ARXIV_CATEGORY_REGEX.MatchString(category) {
+				errs = append(errs, fmt.Errorf("category %q is not an arXiv category like cs.AI", category))
+			}
+		}
+	case HACKERNEWS_SOURCE, MEDIUM_SOURCE:
+	case "":
+		errs = append(errs, errors.New("type is required"))
+	default:
+		errs = append(errs, fmt.Errorf("unknown type %q", source.Type))
+	}
+	if source.URL != "" {
+		if parsed_url, err := url.Parse(source.URL); err != nil || (parsed_url.Scheme != "http" && parsed_url.Scheme != "https") || parsed_url.Host == "" {
+			errs = append(errs, fmt.Errorf("url %q is not an absolute http(s) URL", source.URL))
+		}
+	}
+	if source.Schedule != "" {
+		if _, err := cron.ParseStandard(source.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("schedule %q: %w", source.Schedule, err))
+		}
+	}
+	if source.Days < 0 {
+		errs = append(errs, fmt.Errorf("days must be positive, got %d", source.Days))
+	}
+	if source.Rules != nil {
+		if source.Rules.MaxPages < 0 {
+			errs = append(errs, fmt.Errorf("max pages must be positive, got %d", source.Rules.MaxPages))
+		}
+		for _, rule := range source.Rules.DisallowedURLs {
+			if _, err := regexp.Compile(rule); err != nil {
+				errs = append(errs, fmt.Errorf("disallowed url %q is not a valid regular expression: %w", rule, err))
+			}
+		}
+	}
+	if source.HTTP != nil && source.HTTP.Proxy != "" {
+		if proxy_url, err := url.Parse(source.HTTP.Proxy); err != nil || proxy_url.Host == "" {
+			errs = append(errs, fmt.Errorf("proxy %q is not a valid URL", source.HTTP.Proxy))
+		}
+	}
+	if source.HTTP != nil && source.HTTP.CABundle != "" {
+		if _, err := os.Stat(source.HTTP.CABundle); err != nil {
+			errs = append(errs, fmt.Errorf("ca bundle: %w", err))
+		}
+	}
+	return errs
+}
+
+func (source Source) jsonMapping() *loaders.JSONMapping {
+	if source.Mapping != nil {
+		return source.Mapping
+	}
+	preset := loaders.JSON_PRESETS[source.Preset]
+	return &preset
+}
+
+func defaultSourceName(source *Source) string {
+	if parsed_url, err := url.Parse(source.URL); err == nil && parsed_url.Host != "" {
+		return parsed_url.Host
+	}
+	return source.Type
+}
+
+func (source Source) IsEnabled() bool {
+	return source.Enabled == nil || *source.Enabled
+}
+
+// creates a fresh loader for the source
+func (source Source) NewLoader() *loaders.WebLoader {
+	return source.newLoader(nil)
+}
+
+// customize can adjust the config before the loader is created. nil leaves it as is
+func (source Source) newLoader(customize func(config *loaders.WebLoaderConfig)) *loaders.WebLoader {
+	config := source.loaderConfig(customize)
+	switch source.Type {
+	case HACKERNEWS_SOURCE:
+		config.BaseURL = source.URL
+		return loaders.NewYCHackerNewsSiteLoaderWithConfig(config)
+	case MEDIUM_SOURCE:
+		config.BaseURL = source.URL
+		return loaders.NewMediumSiteLoaderWithConfig(source.Days, config)
+	case FEED_SOURCE:
+		config.Sitemap = source.URL
+		return loaders.NewFeedLoader(source.Days, config)
+	case GITHUB_SOURCE:
+		config.BaseURL = source.URL
+		return loaders.NewGitHubReleaseLoader(source.Days, source.Repos, config)
+	case ARXIV_SOURCE:
+		config.BaseURL = source.URL
+		return loaders.NewArxivLoader(source.Days, source.Categories, config)
+	case JSON_SOURCE:
+		config.Sitemap = source.URL
+		return loaders.NewJSONAggregatorLoader(source.Days, source.jsonMapping(), config)
+	default:
+		config.Sitemap = source.URL
+		return loaders.NewNewsSitemapLoader(source.Days, config)
+	}
+}
+
+// the loader config of the source without the type specific settings
+func (source Source) loaderConfig(customize func(config *loaders.WebLoaderConfig)) *loaders.WebLoaderConfig {
+	config := &loaders.WebLoaderConfig{
+		Name:       source.Name,
+		LocalCache: os.Getenv("CACHE_DIR"),
+		HTTP:       source.HTTP,
+	}
+	if source.Rules != nil {
+		config.BodySelector = source.Rules.BodySelector
+		config.MaxPages = source.Rules.MaxPages
+		if len(source.Rules.DisallowedURLs) > 0 {
+			config.DisallowedFilters = append([]string{loaders.MEDIA_FILTER}, source.Rules.DisallowedURLs...)
+		}
+	}
+	if customize != nil {
+		customize(config)
+	}
+	return config
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSourcesReportsEveryError(t *testing.T) {
+	path := writeFile(t, "sources.yaml", `
+sources:
+  - type: sitemap
+  - type: rss
+    url: https://hackaday.com/feed/
+  - type: json
+    preset: digg
+  - type: github
+    repos: [gocolly]
+  - type: arxiv
+    categories: ["Computer Science"]
+  - type: feed
+    url: hackaday.com/feed/
+    schedule: every hour
+    days: -1
+  - name: hackaday
+    type: feed
+    url: https://hackaday.com/feed/
+  - name: hackaday
+    type: sitemap
+    url: https://hackaday.com/news-sitemap.xml
+    rules:
+      disallowed_urls: ["(unclosed"]
+`)
+	_, err := ReadSources(path)
+	if err == nil {
+		t.Fatal("expected the sources to be rejected")
+	}
+	for _, want := range []string{
+		"source 1 (sitemap): url is required for sitemap sources",
+		`source 2 (hackaday.com): unknown type "rss"`,
+		`source 3 (json): unknown preset "digg"`,
+		`source 4 (github): repo "gocolly" is not owner/name`,
+		`source 5 (arxiv): category "Computer Science" is not an arXiv category`,
+		`source 6 (feed): url "hackaday.com/feed/" is not an absolute http(s) URL`,
+		`source 6 (feed): schedule "every hour"`,
+		"source 6 (feed): days must be positive, got -1",
+		"source 8 (hackaday): name is already used by source 7",
+		`source 8 (hackaday): disallowed url "(unclosed" is not a valid regular expression`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in the errors, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestReadSourcesRejectsUnknownFields(t *testing.T) {
+	path := writeFile(t, "sources.yaml", `
+sources:
+  - type: sitemap
+    url: https://hackaday.com/news-sitemap.xml
+    day: 3
+`)
+	if _, err := ReadSources(path); err == nil || !strings.Contains(err.Error(), "day") {
+		t.Errorf("expected the misspelled field to be reported, got %v", err)
+	}
+}
+
+func TestReadSourcesCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []string
+	}{
+		{
+			name: "with the sitemap column",
+			csv:  "sitemap,type\nhttps://hackaday.com/news-sitemap.xml,sitemap\nhttps://www.darkreading.com/googlenews.xml,sitemap\n",
+			want: []string{"https://hackaday.com/news-sitemap.xml", "https://www.darkreading.com/googlenews.xml"},
+		},
+		{
+			// the sitemap was always read from the first column
+			name: "with a different header",
+			csv:  "url\nhttps://hackaday.com/news-sitemap.xml\n",
+			want: []string{"https://hackaday.com/news-sitemap.xml"},
+		},
+		{
+			name: "with repeated sitemaps",
+			csv:  "sitemap\nhttps://hackaday.com/news-sitemap.xml\nhttps://hackaday.com/news-sitemap.xml\n\n",
+			want: []string{"https://hackaday.com/news-sitemap.xml"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sources, err := ReadSources(writeFile(t, "sitemaps.csv", test.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// hacker news is always collected with the sitemaps
+			if len(sources) != len(test.want)+1 || sources[len(sources)-1].Type != HACKERNEWS_SOURCE {
+				t.Fatalf("expected %d sitemaps and hacker news, got %+v", len(test.want), sources)
+			}
+			for i, url := range test.want {
+				if sources[i].Type != SITEMAP_SOURCE || sources[i].URL != url || sources[i].Name != url || sources[i].Days != _DEFAULT_DAYS {
+					t.Errorf("expected a sitemap source for %s, got %+v", url, sources[i])
+				}
+			}
+		})
+	}
+}
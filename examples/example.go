@@ -1,14 +1,17 @@
 package examples
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"time"
 
 	ds "github.com/soumitsalman/beansack/sdk"
 	"github.com/soumitsalman/newscollector/collector"
+	"github.com/soumitsalman/newscollector/metrics"
 )
 
 const _SITEMAPS = "./examples/sitemaps.csv"
@@ -66,3 +69,18 @@ func localFileStore(contents []ds.Bean) {
 // 	}
 // 	return bean_sack_client
 // }
+
+const _SOURCES = "./examples/sources.yaml"
+
+// keeps collecting from each source on its own schedule until interrupted.
+// serves prometheus metrics on METRICS_ADDR (e.g. ":9090") if it is set
+func StoreLocalScheduled() {
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		go func() {
+			log.Println("FAILED serving metrics", metrics.Serve(addr))
+		}()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	collector.NewScheduler(collector.NewCollector(_SOURCES, localFileStore), "./schedule_state.json").Run(ctx)
+}
@@ -0,0 +1,124 @@
+package loaders
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	ARXIV_SOURCE = "arXiv"
+	_ARXIV_BASE  = "https://export.arxiv.org/api"
+	// arXiv asks API users to keep to one request every 3 seconds
+	_ARXIV_DELAY     = 3 * time.Second
+	_ARXIV_PAGE_SIZE = 200
+)
+
+var _ARXIV_VERSION_REGEX = regexp.MustCompile(`v\d+$`)
+
+// //	ARXIV LOADER		////
+// loads the papers submitted in the last N days to the categories (e.g. cs.AI, cs.CL) from the arXiv Atom API.
+// the listing is sorted by submission date so it is paged through until the papers fall out of the days window.
+// the abstract is the text and the PDF is the media of the paper, nothing else is fetched.
+// the /list/<category> HTML pages are not read yet, that is left for a follow-up. the API has the same papers with
+// structured metadata and arXiv asks automated clients to use it. config.BaseURL can point the loader to somewhere other than https://export.arxiv.org/api
+func NewArxivLoader(days int, categories []string, config *WebLoaderConfig) *WebLoader {
+	if config.BaseURL == "" {
+		config.BaseURL = _ARXIV_BASE
+	}
+	if config.Sitemap == "" {
+		config.Sitemap = arxivQueryURL(config.BaseURL, categories, 0)
+	}
+	web_collector := internalNewLoader(config)
+	web_collector.collector.AllowURLRevisit = true
+	web_collector.collector.Limit(&colly.LimitRule{DomainGlob: "*arxiv.org*", Delay: _ARXIV_DELAY})
+	query_prefix := config.BaseURL + "/query?"
+
+	web_collector.collector.OnResponse(func(r *colly.Response) {
+		// matched by the URL it was requested with in case the API redirects, e.g. from http to https
+		query_url := web_collector.requestURL(r.Request)
+		if !strings.HasPrefix(query_url, query_prefix) {
+			return
+		}
+		var feed feedXML
+		if err := xml.Unmarshal(r.Body, &feed); err != nil {
+			return
+		}
+		in_range := 0
+		for _, entry := range feed.Entries {
+			date := parseDate(entry.published())
+			if !web_collector.withinDateRange(date, days) {
+				continue
+			}
+			in_range++
+			article := entry.toPaper()
+			if article.URL == "" || web_collector.inCache(article.URL) {
+				continue
+			}
+			article.PublishDate = date.Unix()
+			web_collector.discover(article)
+		}
+		// a full page of recent papers means there are more on the next one
+		if len(feed.Entries) == _ARXIV_PAGE_SIZE && in_range == len(feed.Entries) {
+			var start int
+			if parsed_url, err := url.Parse(query_url); err == nil {
+				start, _ = strconv.Atoi(parsed_url.Query().Get("start"))
+			}
+			r.Request.Visit(arxivQueryURL(config.BaseURL, categories, start+_ARXIV_PAGE_SIZE))
+		}
+	})
+	return web_collector
+}
+
+func arxivQueryURL(base_url string, categories []string, start int) string {
+	terms := make([]string, len(categories))
+	for i, category := range categories {
+		terms[i] = "cat:" + strings.TrimSpace(category)
+	}
+	query := url.Values{
+		"search_query": {strings.Join(terms, " OR ")},
+		"sortBy":       {"submittedDate"},
+		"sortOrder":    {"descending"},
+		"start":        {strconv.Itoa(start)},
+		"max_results":  {strconv.Itoa(_ARXIV_PAGE_SIZE)},
+	}
+	return fmt.Sprintf("%s/query?%s", base_url, query.Encode())
+}
+
+func (entry feedItem) toPaper() *Document {
+	article := &Document{
+		URL:    arxivAbstractURL(entry.link()),
+		Title:  strings.Join(strings.Fields(entry.Title), " "),
+		Text:   strings.Join(strings.Fields(entry.Summary), " "),
+		Source: ARXIV_SOURCE,
+		Kind:   PAPER,
+	}
+	// arXiv gives every author with their affiliation when the submitter filled it in
+	article.SetAuthors(entry.authors()...)
+	for _, category := range entry.Categories {
+		if category.Term != "" && !slices.Contains(article.Keywords, category.Term) {
+			article.Keywords = append(article.Keywords, category.Term)
+		}
+	}
+	for _, link := range entry.Links {
+		if link.Title == "pdf" || link.Type == "application/pdf" {
+			article.Media = &Media{URL: strings.Replace(link.Href, "http://", "https://", 1), MimeType: "application/pdf"}
+			break
+		}
+	}
+	article.Quality = ClassifyQuality(article.Text, nil)
+	return article
+}
+
+// https://arxiv.org/abs/2405.12345 for http://arxiv.org/abs/2405.12345v2 so that every version is the same paper
+func arxivAbstractURL(abs_url string) string {
+	abs_url = strings.Replace(strings.TrimSpace(abs_url), "http://", "https://", 1)
+	return _ARXIV_VERSION_REGEX.ReplaceAllString(abs_url, "")
+}
@@ -0,0 +1,182 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type Author struct {
+	Name string `json:"name"`
+	// profile page
+	URL string `json:"url,omitempty"`
+	// organization or affiliation
+	Org string `json:"org,omitempty"`
+}
+
+var (
+	_BYLINE_PREFIX_REGEX = regexp.MustCompile(`(?i)^(written\s+|posted\s+|reported\s+)?by[:\s]+`)
+	_AUTHOR_SPLIT_REGEX  = regexp.MustCompile(`(?i)\s*[,;]\s*|\s+(?:&|and)\s+`)
+	// "Jane Doe, Jr." is one author
+	_NAME_SUFFIX_REGEX = regexp.MustCompile(`(?i)^(jr|sr|phd|ph\.d|md|ii|iii|iv)\.?$`)
+	// rss style "jane@example.com (Jane Doe)"
+	_EMAIL_NAME_REGEX = regexp.MustCompile(`^\S+@\S+\s*\((.+)\)$`)
+)
+
+// //	AUTHORS		////
+// bylines come in every shape: "By Jane Doe and John Roe", "Jane Doe, John Roe & Mary Major" or a single JSON-LD
+// person. every extraction path goes through SetAuthors so that Authors holds one cleaned up entry per person and
+// Author stays the joined string that older consumers read. only free text bylines are split (ParseAuthors), names
+// from structured fields are taken as they are since "Doe, Jane" or "Acme, Inc." is one author there
+func (article *Document) SetAuthors(authors ...Author) {
+	article.Authors = NormalizeAuthors(authors...)
+	article.Author = JoinAuthors(article.Authors)
+}
+
+// the authors in a free text byline, e.g. from a meta tag, an rss author or a pdf
+func ParseAuthors(byline string) []Author {
+	names := splitAuthorNames(byline)
+	authors := make([]Author, len(names))
+	for i, name := range names {
+		authors[i] = Author{Name: name}
+	}
+	return NormalizeAuthors(authors...)
+}
+
+// collapses the whitespace and drops the empty and repeated ones. names are not split
+func NormalizeAuthors(authors ...Author) []Author {
+	normalized := make([]Author, 0, len(authors))
+	seen := make(map[string]bool)
+	for _, author := range authors {
+		name := strings.Join(strings.Fields(author.Name), " ")
+		if key := strings.ToLower(name); name != "" && !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, Author{Name: name, URL: strings.TrimSpace(author.URL), Org: strings.TrimSpace(author.Org)})
+		}
+	}
+	return normalized
+}
+
+// names separated by ", "
+func JoinAuthors(authors []Author) string {
+	names := make([]string, len(authors))
+	for i, author := range authors {
+		names[i] = author.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func splitAuthorNames(byline string) []string {
+	byline = strings.Join(strings.Fields(byline), " ")
+	if match := _EMAIL_NAME_REGEX.FindStringSubmatch(byline); match != nil {
+		byline = match[1]
+	}
+	byline = _BYLINE_PREFIX_REGEX.ReplaceAllString(byline, "")
+	names := make([]string, 0)
+	for _, part := range _AUTHOR_SPLIT_REGEX.Split(byline, -1) {
+		part = strings.Trim(part, " .-|")
+		switch {
+		case part == "":
+		case _NAME_SUFFIX_REGEX.MatchString(part) && len(names) > 0:
+			names[len(names)-1] += ", " + part + "."
+		default:
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// //	AUTHORS FROM HTML		////
+// JSON-LD first since it is the most structured, then the author meta tags and rel=author links. nil if the page doesn't say
+func readHTMLAuthors(body []byte, page_url *url.URL) []Author {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	var authors []Author
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, script *goquery.Selection) bool {
+		var data any
+		if json.Unmarshal([]byte(script.Text()), &data) == nil {
+			authors = jsonLDAuthors(data)
+		}
+		return len(authors) == 0
+	})
+	if len(authors) > 0 {
+		return NormalizeAuthors(authors...)
+	}
+	doc.Find(`meta[name="author"], meta[property="article:author"]`).Each(func(_ int, meta *goquery.Selection) {
+		value := strings.TrimSpace(meta.AttrOr("content", ""))
+		// article:author is often the profile page
+		if profile_url, err := url.Parse(value); err == nil && profile_url.Scheme != "" && profile_url.Host != "" {
+			return
+		}
+		// the meta tags are free text
+		authors = append(authors, ParseAuthors(value)...)
+	})
+	if len(authors) == 0 {
+		doc.Find(`a[rel="author"]`).Each(func(_ int, link *goquery.Selection) {
+			link_authors := ParseAuthors(link.Text())
+			// the profile page only belongs to the name if the link has just one
+			if href, ok := link.Attr("href"); ok && len(link_authors) == 1 {
+				if profile_url, err := page_url.Parse(href); err == nil {
+					link_authors[0].URL = profile_url.String()
+				}
+			}
+			authors = append(authors, link_authors...)
+		})
+	}
+	return NormalizeAuthors(authors...)
+}
+
+// the "author" of the first object that has one, looking through arrays and @graph
+func jsonLDAuthors(node any) []Author {
+	switch val := node.(type) {
+	case []any:
+		for _, child := range val {
+			if authors := jsonLDAuthors(child); len(authors) > 0 {
+				return authors
+			}
+		}
+	case map[string]any:
+		if author, ok := val["author"]; ok {
+			return jsonLDPersons(author)
+		}
+		if graph, ok := val["@graph"]; ok {
+			return jsonLDAuthors(graph)
+		}
+	}
+	return nil
+}
+
+// a person can be a name, a Person/Organization object or an array of either
+func jsonLDPersons(node any) []Author {
+	switch val := node.(type) {
+	case string:
+		return []Author{{Name: val}}
+	case []any:
+		authors := make([]Author, 0, len(val))
+		for _, child := range val {
+			authors = append(authors, jsonLDPersons(child)...)
+		}
+		return authors
+	case map[string]any:
+		name, _ := val["name"].(string)
+		if name == "" {
+			return nil
+		}
+		author := Author{Name: name}
+		author.URL, _ = val["url"].(string)
+		for _, key := range []string{"affiliation", "worksFor"} {
+			if orgs := jsonLDPersons(val[key]); len(orgs) > 0 {
+				author.Org = orgs[0].Name
+				break
+			}
+		}
+		return []Author{author}
+	}
+	return nil
+}
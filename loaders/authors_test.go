@@ -0,0 +1,34 @@
+package loaders
+
+import "testing"
+
+func TestStructuredAuthorsAreNotSplit(t *testing.T) {
+	var article Document
+	article.SetAuthors(
+		Author{Name: " Doe,  Jane ", URL: "https://example.com/jane"},
+		Author{Name: "Acme, Inc.", Org: "Acme"},
+		Author{Name: "doe, jane"},
+	)
+	if len(article.Authors) != 2 {
+		t.Fatalf("expected 2 authors, got %+v", article.Authors)
+	}
+	if got := article.Authors[0]; got.Name != "Doe, Jane" || got.URL != "https://example.com/jane" {
+		t.Errorf("expected Doe, Jane with her profile, got %+v", got)
+	}
+	if got := article.Authors[1]; got.Name != "Acme, Inc." || got.Org != "Acme" {
+		t.Errorf("expected Acme, Inc. with its org, got %+v", got)
+	}
+}
+
+func TestBylinesAreSplit(t *testing.T) {
+	authors := ParseAuthors("By Jane Doe, John Roe, Jr. & Mary Major")
+	want := []string{"Jane Doe", "John Roe, Jr.", "Mary Major"}
+	if len(authors) != len(want) {
+		t.Fatalf("expected %v, got %+v", want, authors)
+	}
+	for i, name := range want {
+		if authors[i].Name != name {
+			t.Errorf("expected %s, got %s", name, authors[i].Name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package loaders
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// //	CONTENT-TYPE DISPATCH		////
+// article links don't always point to html. the response Content-Type (or the content itself when the server
+// doesn't say) decides how the text is read: html goes through readability and its fallbacks, pdfs through
+// a pdf text extractor, plain text and markdown are taken as is and everything else is skipped with a reason
+
+const (
+	_HTML_CONTENT     = "html"
+	_PDF_CONTENT      = "pdf"
+	_TEXT_CONTENT     = "text"
+	_SKIPPED_CONTENT  = "skipped"
+	_GENERIC_CONTENT  = "application/octet-stream"
+	_PDF_MAGIC_NUMBER = "%PDF-"
+)
+
+// which of the extractors handles the content
+func contentKind(content_type string, body []byte) (string, string) {
+	media_type, _, _ := mime.ParseMediaType(content_type)
+	if media_type == "" || media_type == _GENERIC_CONTENT {
+		// servers often don't know what they are sending
+		if bytes.HasPrefix(body, []byte(_PDF_MAGIC_NUMBER)) {
+			return _PDF_CONTENT, "application/pdf"
+		}
+		media_type, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	switch {
+	case media_type == "text/html" || media_type == "application/xhtml+xml":
+		return _HTML_CONTENT, media_type
+	case media_type == "application/pdf" || media_type == "application/x-pdf":
+		return _PDF_CONTENT, media_type
+	case media_type == "text/plain" || media_type == "text/markdown" || media_type == "text/x-markdown":
+		return _TEXT_CONTENT, media_type
+	default:
+		return _SKIPPED_CONTENT, media_type
+	}
+}
+
+// reads the text of a document that is not html. sets SkipReason for content that has no readable text
+func readNonHTMLIntoDocument(article *Document, content_kind, media_type string, body []byte, page_url *url.URL) {
+	if article.Source == "" {
+		article.Source = page_url.Host
+	}
+	if article.Kind == "" {
+		article.Kind = ARTICLE
+	}
+	switch content_kind {
+	case _PDF_CONTENT:
+		text, title, author, err := readPDF(body)
+		if err != nil {
+			article.SkipReason = fmt.Sprintf("unreadable pdf: %v", err)
+			break
+		}
+		article.Text = text
+		if article.Title == "" {
+			article.Title = title
+		}
+		if len(article.Authors) == 0 {
+			article.SetAuthors(ParseAuthors(author)...)
+		}
+	case _TEXT_CONTENT:
+		article.Text = strings.TrimSpace(string(body))
+		if article.Title == "" {
+			article.Title = markdownTitle(article.Text)
+		}
+	default:
+		article.SkipReason = skipReason(media_type)
+		// still worth keeping as a media document even though there is no text
+		if kind := mediaKind(media_type, ""); kind != "" && article.Kind == ARTICLE {
+			article.Kind = kind
+			article.Media = &Media{URL: page_url.String(), MimeType: media_type}
+		}
+	}
+	// the file name is better than nothing
+	if name := path.Base(page_url.Path); article.Title == "" && name != "/" && name != "." {
+		article.Title = name
+	}
+	article.Quality = ClassifyQuality(article.Text, nil)
+}
+
+func skipReason(media_type string) string {
+	switch strings.SplitN(media_type, "/", 2)[0] {
+	case "image", "audio", "video":
+		return "binary media: " + media_type
+	default:
+		return "unsupported content type: " + media_type
+	}
+}
+
+// text of every page and the title and author from the document info
+func readPDF(body []byte) (text string, title string, author string, err error) {
+	// the parser panics on some malformed files
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("%v", recovered)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return "", "", "", err
+	}
+	plain_text, err := reader.GetPlainText()
+	if err != nil {
+		return "", "", "", err
+	}
+	text_bytes, err := io.ReadAll(plain_text)
+	if err != nil {
+		return "", "", "", err
+	}
+	info := reader.Trailer().Key("Info")
+	return strings.TrimSpace(string(text_bytes)), strings.TrimSpace(info.Key("Title").Text()), strings.TrimSpace(info.Key("Author").Text()), nil
+}
+
+// the first heading of a markdown document. "" for plain text
+func markdownTitle(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "# ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "# "))
+		}
+	}
+	return ""
+}
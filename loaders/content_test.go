@@ -0,0 +1,80 @@
+package loaders_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+const _CONTENT_TESTDATA = "testdata/content"
+
+// serves the files in testdata/content with the Content-Type in the content_type query parameter
+func serveContent(t *testing.T) *httptest.Server {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := os.ReadFile(filepath.Join(_CONTENT_TESTDATA, filepath.Base(r.URL.Path)))
+		if err != nil {
+			body = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+		}
+		w.Header().Set("Content-Type", r.URL.Query().Get("content_type"))
+		w.Write(body)
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+func TestLoadDocumentByContentType(t *testing.T) {
+	site := serveContent(t)
+	tests := []struct {
+		name         string
+		path         string
+		content_type string
+		kind         string
+		title        string
+		author       string
+		skip_reason  string
+	}{
+		{"pdf", "/night-buses.pdf", "application/pdf", loaders.ARTICLE, "Night buses", "Jane Doe", ""},
+		// servers often send pdfs as a download
+		{"pdf as a download", "/night-buses.pdf", "application/octet-stream", loaders.ARTICLE, "Night buses", "Jane Doe", ""},
+		{"plain text", "/night-buses.txt", "text/plain; charset=utf-8", loaders.ARTICLE, "night-buses.txt", "", ""},
+		{"markdown", "/night-buses.md", "text/markdown", loaders.ARTICLE, "Night buses", "", ""},
+		{"image", "/cover.png", "image/png", loaders.IMAGE, "cover.png", "", "binary media: image/png"},
+		{"archive", "/night-buses.zip", "application/zip", loaders.ARTICLE, "night-buses.zip", "", "unsupported content type: application/zip"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := loaders.NewDefaultWebTextLoader(&loaders.WebLoaderConfig{}).LoadDocument(site.URL + test.path + "?content_type=" + url.QueryEscape(test.content_type))
+			if doc == nil {
+				t.Fatal("expected a document")
+			}
+			if doc.Kind != test.kind || doc.Title != test.title || doc.Author != test.author || doc.SkipReason != test.skip_reason {
+				t.Errorf("expected %s %q by %q skipped for %q, got %s %q by %q skipped for %q",
+					test.kind, test.title, test.author, test.skip_reason, doc.Kind, doc.Title, doc.Author, doc.SkipReason)
+			}
+			if has_text := strings.Contains(doc.Text, loadertest.ARTICLE_PHRASE); has_text != (test.skip_reason == "") {
+				t.Errorf("expected text only when nothing was skipped, got %q", doc.Text)
+			}
+		})
+	}
+}
+
+func TestUnreadablePDFIsSkipped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.pdf")
+	if err := os.WriteFile(path, []byte("%PDF-1.4\nnot really a pdf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loaders.ExtractFromFile(path, "https://citydesk.example.com/broken.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Text != "" || !strings.HasPrefix(doc.SkipReason, "unreadable pdf") {
+		t.Errorf("expected the pdf to be skipped as unreadable, got %q: %q", doc.SkipReason, doc.Text)
+	}
+}
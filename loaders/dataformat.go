@@ -7,22 +7,65 @@ import (
 	datautils "github.com/soumitsalman/data-utils"
 )
 
+const _PREVIEW_LENGTH = 150
+
 type Document struct {
-	Kind        string   `json:"kind,omitempty"`
-	URL         string   `json:"url,omitempty"`
-	Source      string   `json:"source,omitempty"`
-	Title       string   `json:"title,omitempty"`
-	Text        string   `json:"text,omitempty"`
-	Author      string   `json:"author,omitempty"`
+	// one of ARTICLE, PODCAST, VIDEO, IMAGE, RELEASE or PAPER
+	Kind    string `json:"kind,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Summary string `json:"summary,omitempty"` // a few sentences picked from Text
+	// names of Authors joined by ", ". set with SetAuthors
+	Author string `json:"author,omitempty"`
+	// one per person in the order of the byline
+	Authors     []Author `json:"authors,omitempty"`
 	PublishDate int64    `json:"created,omitempty"`
 	Keywords    []string `json:"keywords,omitempty"`
 	Comments    int      `json:"comments,omitempty"`
 	Likes       int      `json:"likes,omitempty"`
+	// version of a RELEASE
+	Version string `json:"version,omitempty"`
+	// where Comments and Likes can be polled again. see FetchEngagement
+	EngagementURL string `json:"engagement_url,omitempty"`
+	// one of QUALITY_OK, QUALITY_EMPTY, QUALITY_TRUNCATED, QUALITY_PAYWALLED, QUALITY_COOKIE_WALL
+	Quality string `json:"quality,omitempty"`
+	// why there is no text, e.g. the link pointed to an image
+	SkipReason string `json:"skip_reason,omitempty"`
+	// assigned from the source configuration
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// near-duplicate cluster this document belongs to and the URL of the original in that cluster (empty if this is the original)
+	ClusterId    string `json:"cluster_id,omitempty"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	// the audio, video or image file of a PODCAST, VIDEO or IMAGE and the PDF of a PAPER.
+	// only the Thumbnail for an ARTICLE whose feed item came with a picture
+	Media *Media `json:"media,omitempty"`
+}
+
+type Media struct {
+	URL      string `json:"url,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	// in seconds
+	Duration    int64  `json:"duration,omitempty"`
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// short stand-in for the text. the summary if there is one, otherwise the beginning of the text
+func (c *Document) Preview() string {
+	if c.Summary != "" {
+		return c.Summary
+	}
+	return datautils.TruncateTextWithEllipsis(c.Text, _PREVIEW_LENGTH)
 }
 
+// json of the document with the preview in place of the full text
 func (c *Document) String() string {
-	// TODO: remove. temp for debugging
-	c.Text = datautils.TruncateTextWithEllipsis(c.Text, 150)
-	json_data, _ := json.MarshalIndent(c, "", "\t")
+	preview := *c
+	preview.Text = c.Preview()
+	preview.Summary = ""
+	json_data, _ := json.MarshalIndent(&preview, "", "\t")
 	return fmt.Sprint(string(json_data))
 }
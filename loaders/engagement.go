@@ -0,0 +1,144 @@
+package loaders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const _REDDIT_BASE = "https://www.reddit.com"
+
+// comments and likes of a story at the time it was polled
+type Engagement struct {
+	// id of the story on the site, e.g. the hacker news item id or the reddit fullname (t3_...)
+	ContentId string
+	// subreddit. "" for hacker news
+	Channel string
+	// discussion page of the story
+	ContainerURL string
+	Comments     int
+	Likes        int
+	// share of upvotes. only reddit has it
+	LikesRatio float64
+}
+
+// //	ENGAGEMENT POLLING		////
+// comments and likes keep going up for days after a story is collected. Document.EngagementURL is where they can be
+// polled again without fetching the article: the item JSON for hacker news, the .json of a post or listing for reddit,
+// the story JSON for lobsters.
+// the request is cancelled when ctx is done
+func FetchEngagement(ctx context.Context, fetcher Fetcher, engagement_url string) (*Engagement, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, engagement_url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := fetcher.Fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", engagement_url, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if engagement := readHackerNewsEngagement(body); engagement != nil {
+		return engagement, nil
+	}
+	if engagement := readRedditEngagement(body); engagement != nil {
+		return engagement, nil
+	}
+	if engagement := readLobstersEngagement(body); engagement != nil {
+		return engagement, nil
+	}
+	return nil, fmt.Errorf("%s: neither a hacker news item, a reddit post nor a lobsters story", engagement_url)
+}
+
+// https://hacker-news.firebaseio.com/v0/item/8863.json
+func readHackerNewsEngagement(body []byte) *Engagement {
+	var item struct {
+		Id    int64   `json:"id"`
+		Kids  []int64 `json:"kids"`
+		Score int     `json:"score"`
+		Type  string  `json:"type"`
+	}
+	if json.Unmarshal(body, &item) != nil || item.Id == 0 || item.Type == "" {
+		return nil
+	}
+	return &Engagement{
+		ContentId:    strconv.FormatInt(item.Id, 10),
+		ContainerURL: fmt.Sprintf("https://news.ycombinator.com/item?id=%d", item.Id),
+		// counted the same way as when the story was discovered
+		Comments: len(item.Kids),
+		Likes:    item.Score,
+	}
+}
+
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Kind string `json:"kind"`
+			Data struct {
+				Name        string  `json:"name"`
+				Subreddit   string  `json:"subreddit"`
+				Permalink   string  `json:"permalink"`
+				NumComments int     `json:"num_comments"`
+				Score       int     `json:"score"`
+				UpvoteRatio float64 `json:"upvote_ratio"`
+			} `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+// the first post in a listing (/by_id/t3_....json) or in the post page (/r/.../comments/....json), which is an array of
+// the post listing and the comment listing
+func readRedditEngagement(body []byte) *Engagement {
+	var listings []redditListing
+	if json.Unmarshal(body, &listings) != nil {
+		var listing redditListing
+		if json.Unmarshal(body, &listing) != nil {
+			return nil
+		}
+		listings = []redditListing{listing}
+	}
+	for _, listing := range listings {
+		for _, child := range listing.Data.Children {
+			if child.Kind != "t3" {
+				continue
+			}
+			return &Engagement{
+				ContentId:    child.Data.Name,
+				Channel:      child.Data.Subreddit,
+				ContainerURL: _REDDIT_BASE + child.Data.Permalink,
+				Comments:     child.Data.NumComments,
+				Likes:        child.Data.Score,
+				LikesRatio:   child.Data.UpvoteRatio,
+			}
+		}
+	}
+	return nil
+}
+
+// https://lobste.rs/s/x7kqpz.json
+func readLobstersEngagement(body []byte) *Engagement {
+	var story struct {
+		ShortId      string `json:"short_id"`
+		CommentsURL  string `json:"comments_url"`
+		CommentCount int    `json:"comment_count"`
+		Score        int    `json:"score"`
+	}
+	if json.Unmarshal(body, &story) != nil || story.ShortId == "" || story.CommentsURL == "" {
+		return nil
+	}
+	return &Engagement{
+		ContentId:    story.ShortId,
+		ContainerURL: story.CommentsURL,
+		Comments:     story.CommentCount,
+		Likes:        story.Score,
+	}
+}
@@ -0,0 +1,23 @@
+package loaders_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+func TestFetchLobstersEngagement(t *testing.T) {
+	fetcher := &loadertest.PagesFetcher{Pages: map[string]string{
+		"https://lobste.rs/s/x7kqpz.json": `{"short_id": "x7kqpz", "short_id_url": "https://lobste.rs/s/x7kqpz", "score": 61, "comment_count": 15,
+			"comments_url": "https://lobste.rs/s/x7kqpz/mesh_network_on_coin_cell", "submitter_user": "jcs"}`,
+	}}
+	engagement, err := loaders.FetchEngagement(context.Background(), fetcher, "https://lobste.rs/s/x7kqpz.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if engagement.ContentId != "x7kqpz" || engagement.Comments != 15 || engagement.Likes != 61 {
+		t.Errorf("expected the lobsters story engagement, got %+v", engagement)
+	}
+}
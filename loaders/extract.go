@@ -0,0 +1,65 @@
+package loaders
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// //	OFFLINE EXTRACTION		////
+// runs the same extraction as the loaders on html that is already at hand, e.g. from an archive, an email
+// newsletter or a headless browser. nothing is fetched so the AMP and print fallbacks are skipped.
+// base_url resolves the relative links and becomes the Document's URL and Source
+func ExtractFromHTML(reader io.Reader, base_url string) (*Document, error) {
+	page_url, err := url.Parse(base_url)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	article, err := newDocumentFromHTML(body, page_url)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", base_url, err)
+	}
+	article.Text = readBodyWithFallbacks(body, page_url, nil)
+	article.Quality = ClassifyQuality(article.Text, body)
+	return article, nil
+}
+
+// same as ExtractFromHTML on the content of the file, except that pdf, plain text and markdown files are read
+// like the loaders read them. base_url "" means the file:// URL of the file
+func ExtractFromFile(path string, base_url string) (*Document, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if base_url == "" {
+		abs_path, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		base_url = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs_path)}).String()
+	}
+	content_kind, media_type := contentKind(mime.TypeByExtension(filepath.Ext(path)), body)
+	return extractContent(body, content_kind, media_type, base_url)
+}
+
+// html goes through ExtractFromHTML and everything else is read like the loaders read it
+func extractContent(body []byte, content_kind, media_type, base_url string) (*Document, error) {
+	if content_kind == _HTML_CONTENT {
+		return ExtractFromHTML(bytes.NewReader(body), base_url)
+	}
+	page_url, err := url.Parse(base_url)
+	if err != nil {
+		return nil, err
+	}
+	article := &Document{URL: page_url.String()}
+	readNonHTMLIntoDocument(article, content_kind, media_type, body, page_url)
+	return article, nil
+}
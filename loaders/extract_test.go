@@ -0,0 +1,55 @@
+package loaders_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+func TestExtractFromHTMLResolvesLinksAgainstTheBaseURL(t *testing.T) {
+	page := strings.Replace(loadertest.ArticlePage(), "<h1>Night buses</h1>", `<h1>Night buses</h1><p>By <a rel="author" href="../staff/jane-doe">Jane Doe</a></p>`, 1)
+	doc, err := loaders.ExtractFromHTML(strings.NewReader(page), "https://citydesk.example.com/2024/06/night-buses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.URL != "https://citydesk.example.com/2024/06/night-buses" || doc.Source != "citydesk.example.com" {
+		t.Errorf("expected the base URL to be the document's URL and source, got %s and %s", doc.URL, doc.Source)
+	}
+	if len(doc.Authors) != 1 || doc.Authors[0].URL != "https://citydesk.example.com/2024/staff/jane-doe" {
+		t.Errorf("expected the author link resolved against the base URL, got %+v", doc.Authors)
+	}
+	if doc.Quality != loaders.QUALITY_OK || !strings.Contains(doc.Text, loadertest.ARTICLE_PHRASE) {
+		t.Errorf("expected the article text, got %q: %q", doc.Quality, doc.Text)
+	}
+}
+
+func TestExtractFromHTMLRejectsABadBaseURL(t *testing.T) {
+	if _, err := loaders.ExtractFromHTML(strings.NewReader(loadertest.ArticlePage()), "://citydesk"); err == nil {
+		t.Error("expected an error for a base URL that doesn't parse")
+	}
+}
+
+func TestExtractFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "night-buses.html")
+	if err := os.WriteFile(path, []byte(loadertest.ArticlePage()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := loaders.ExtractFromFile(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.URL, "file://") || !strings.HasSuffix(doc.URL, "/night-buses.html") || !strings.Contains(doc.Text, loadertest.ARTICLE_PHRASE) {
+		t.Errorf("expected the article under the file's URL, got %s: %q", doc.URL, doc.Text)
+	}
+
+	if _, err := loaders.ExtractFromFile(filepath.Join(dir, "missing.html"), ""); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error for a missing file, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-readability"
+)
+
+// //	CLIENT-SIDE RENDERED PAGE FALLBACKS		////
+// when readability comes back (nearly) empty the page was most likely rendered on the client.
+// these are tried in order until one of them produces enough text
+//  1. JSON state embedded in the page by Next.js (__NEXT_DATA__) or redux style apps (window.__INITIAL_STATE__)
+//  2. the AMP version of the page (<link rel=amphtml>)
+//  3. the print view of the page, if the page links to one
+//
+// the other versions are only fetched when the page links to them, so a page costs at most 2 more requests
+
+var (
+	_NEXT_DATA_REGEX     = regexp.MustCompile(`(?is)<script[^>]+id=["']__NEXT_DATA__["'][^>]*>(.*?)</script>`)
+	_INITIAL_STATE_REGEX = regexp.MustCompile(`(?is)window\.__INITIAL_STATE__\s*=\s*(\{.*?\})\s*;?\s*</script>`)
+	// keys in the embedded state that usually hold the article body
+	_BODY_KEYS = map[string]bool{"articleBody": true, "body": true, "content": true, "text": true, "html": true, "contentHtml": true, "bodyHtml": true}
+)
+
+// fetch gets the other versions of the page. nil means nothing can be fetched
+func readBodyWithFallbacks(body []byte, page_url *url.URL, fetch func(*url.URL) ([]byte, *url.URL)) string {
+	text := readBodyFromHTML(body, page_url)
+	if hasEnoughText(text) {
+		return text
+	}
+	if fallback := readBodyFromEmbeddedState(body); hasEnoughText(fallback) {
+		return fallback
+	}
+	// offline extraction has nothing to fetch the other versions of the page with
+	if fetch == nil {
+		return text
+	}
+	for _, fallback_url := range []*url.URL{findAMPURL(body, page_url), findPrintURL(body, page_url)} {
+		if fallback_url == nil {
+			continue
+		}
+		if fallback_body, final_url := fetch(fallback_url); fallback_body != nil {
+			if fallback := readBodyFromHTML(fallback_body, final_url); hasEnoughText(fallback) {
+				return fallback
+			}
+		}
+	}
+	// give up and go with whatever readability found in the first place
+	return text
+}
+
+func readBodyFromHTML(body []byte, page_url *url.URL) string {
+	if raw_article, err := readability.FromReader(bytes.NewReader(body), page_url); err == nil {
+		return raw_article.TextContent
+	}
+	return ""
+}
+
+func hasEnoughText(text string) bool {
+	return len(strings.Fields(text)) >= _MIN_EMPTY_WORDS
+}
+
+// picks the longest body-like string in the embedded state
+func readBodyFromEmbeddedState(body []byte) string {
+	var state_json []byte
+	if match := _NEXT_DATA_REGEX.FindSubmatch(body); match != nil {
+		state_json = match[1]
+	} else if match := _INITIAL_STATE_REGEX.FindSubmatch(body); match != nil {
+		state_json = match[1]
+	} else {
+		return ""
+	}
+	var state any
+	if json.Unmarshal(state_json, &state) != nil {
+		return ""
+	}
+	longest := ""
+	walkJSON(state, func(key, value string) {
+		if _BODY_KEYS[key] && len(value) > len(longest) {
+			longest = value
+		}
+	})
+	// the body is often stored as an html fragment
+	if strings.Contains(longest, "<") {
+		if doc, err := goquery.NewDocumentFromReader(strings.NewReader(longest)); err == nil {
+			return strings.TrimSpace(doc.Text())
+		}
+	}
+	return longest
+}
+
+func walkJSON(node any, visit func(key, value string)) {
+	switch val := node.(type) {
+	case map[string]any:
+		for key, child := range val {
+			if str, ok := child.(string); ok {
+				visit(key, str)
+			} else {
+				walkJSON(child, visit)
+			}
+		}
+	case []any:
+		for _, child := range val {
+			walkJSON(child, visit)
+		}
+	}
+}
+
+func findAMPURL(body []byte, page_url *url.URL) *url.URL {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	if href, ok := doc.Find("link[rel=amphtml]").Attr("href"); ok {
+		if amp_url, err := page_url.Parse(href); err == nil && amp_url.String() != page_url.String() {
+			return amp_url
+		}
+	}
+	return nil
+}
+
+// the print view the page links to with <link media=print> or an anchor following one of the usual conventions. nil if there is none
+func findPrintURL(body []byte, page_url *url.URL) *url.URL {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	if href, ok := doc.Find("link[media=print][href]").Attr("href"); ok {
+		if print_url := sameSiteURL(page_url, href); print_url != nil && !sameURL(print_url, page_url) {
+			return print_url
+		}
+	}
+	candidates := printURLs(page_url)
+	var print_url *url.URL
+	doc.Find("a[href]").EachWithBreak(func(_ int, anchor *goquery.Selection) bool {
+		link := sameSiteURL(page_url, anchor.AttrOr("href", ""))
+		if link == nil {
+			return true
+		}
+		for _, candidate := range candidates {
+			if sameURL(link, candidate) {
+				print_url = link
+				return false
+			}
+		}
+		return true
+	})
+	return print_url
+}
+
+// common print view conventions: ?print=1, ?output=print and /print
+func printURLs(page_url *url.URL) []*url.URL {
+	urls := make([]*url.URL, 0, 3)
+	for _, param := range [][2]string{{"print", "1"}, {"output", "print"}} {
+		print_url := *page_url
+		query := print_url.Query()
+		query.Set(param[0], param[1])
+		print_url.RawQuery = query.Encode()
+		urls = append(urls, &print_url)
+	}
+	print_url := *page_url
+	print_url.Path = strings.TrimSuffix(print_url.Path, "/") + "/print"
+	return append(urls, &print_url)
+}
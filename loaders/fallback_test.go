@@ -0,0 +1,58 @@
+package loaders_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+const _THIN_PAGE = `<html><head><title>Night buses</title>%s</head><body><div id="root">Loading</div></body></html>`
+
+func TestFallbacksOnlyFetchLinkedVersions(t *testing.T) {
+	fetcher := &loadertest.PagesFetcher{Pages: map[string]string{
+		"https://news.example.com/night-buses": fmt.Sprintf(_THIN_PAGE, ""),
+	}}
+	article := loaders.NewDefaultWebTextLoader(&loaders.WebLoaderConfig{Fetcher: fetcher}).LoadDocument("https://news.example.com/night-buses")
+	if article == nil || article.Quality != loaders.QUALITY_EMPTY {
+		t.Fatalf("expected an empty document, got %v", article)
+	}
+	if len(fetcher.Requests) != 1 {
+		t.Errorf("expected no fallback requests for a page that links to no other version, got %d requests", len(fetcher.Requests))
+	}
+}
+
+func TestFallbacksUseTheAMPVersion(t *testing.T) {
+	fetcher := &loadertest.PagesFetcher{Pages: map[string]string{
+		"https://news.example.com/night-buses":         fmt.Sprintf(_THIN_PAGE, `<link rel="amphtml" href="/night-buses/amp">`),
+		"https://news.example.com/night-buses/amp":     loadertest.ArticlePage(),
+		"https://news.example.com/night-buses?print=1": loadertest.ArticlePage(),
+	}}
+	article := loaders.NewDefaultWebTextLoader(&loaders.WebLoaderConfig{Fetcher: fetcher}).LoadDocument("https://news.example.com/night-buses")
+	if article == nil || !strings.Contains(article.Text, loadertest.ARTICLE_PHRASE) {
+		t.Fatalf("expected the text of the AMP version, got %v", article)
+	}
+	if len(fetcher.Requests) != 2 {
+		t.Errorf("expected the page and its AMP version only, got %d requests", len(fetcher.Requests))
+	}
+	// fetched like every other page of the loader
+	if agent := fetcher.Requests[1].Header.Get("User-Agent"); agent == "" || strings.HasPrefix(agent, "Go-http-client") || strings.HasPrefix(agent, "colly") {
+		t.Errorf("expected a random user agent on the AMP request, got %q", agent)
+	}
+}
+
+func TestFallbacksUseTheLinkedPrintView(t *testing.T) {
+	fetcher := &loadertest.PagesFetcher{Pages: map[string]string{
+		"https://news.example.com/night-buses":         strings.Replace(fmt.Sprintf(_THIN_PAGE, ""), "Loading", `Loading <a href="?print=1">Print</a>`, 1),
+		"https://news.example.com/night-buses?print=1": loadertest.ArticlePage(),
+	}}
+	article := loaders.NewDefaultWebTextLoader(&loaders.WebLoaderConfig{Fetcher: fetcher}).LoadDocument("https://news.example.com/night-buses")
+	if article == nil || !strings.Contains(article.Text, loadertest.ARTICLE_PHRASE) {
+		t.Fatalf("expected the text of the print view, got %v", article)
+	}
+	if len(fetcher.Requests) != 2 {
+		t.Errorf("expected the page and its print view only, got %d requests", len(fetcher.Requests))
+	}
+}
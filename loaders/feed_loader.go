@@ -0,0 +1,398 @@
+package loaders
+
+import (
+	"encoding/xml"
+	"mime"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// //	RSS & ATOM FEED LOADER		////
+// loads the items of an RSS or Atom feed (config.Sitemap) published in the last N days. items with an audio or video
+// enclosure (podcasts, YouTube channel feeds) become PODCAST and VIDEO documents carrying the enclosure and are not
+// visited, and so do image items without a link to an article. the other items are visited for their body like the
+// sitemap loaders do. an image enclosure of an article is only its picture and becomes the thumbnail of its Media
+func NewFeedLoader(days int, config *WebLoaderConfig) *WebLoader {
+	if config.Timeout == 0 {
+		config.Timeout = _MAX_TIMEOUT
+	}
+	if config.DisallowedFilters == nil {
+		config.DisallowedFilters = []string{MEDIA_FILTER}
+	}
+	web_collector := internalNewLoader(config)
+	web_collector.collector.AllowURLRevisit = true
+
+	web_collector.collector.OnResponse(func(r *colly.Response) {
+		// the feed may have been redirected so it is matched by the URL it was requested with
+		if web_collector.requestURL(r.Request) != web_collector.Config.Sitemap {
+			return
+		}
+		var feed feedXML
+		if err := xml.Unmarshal(r.Body, &feed); err != nil {
+			return
+		}
+		items := append(feed.Channel.Items, feed.Entries...)
+		for _, item := range items {
+			date := parseDate(item.published())
+			article := item.toDocument(feed.source(r.Request.URL))
+			if article.URL == "" || !web_collector.withinDateRange(date, days) || web_collector.inCache(article.URL) {
+				continue
+			}
+			article.PublishDate = date.Unix()
+			web_collector.discover(article)
+			if article.Kind == ARTICLE {
+				// now collect the body
+				r.Request.Visit(article.URL)
+			}
+		}
+	})
+	web_collector.collector.OnHTML(bodySelector(config, BODY_EXPR), func(h *colly.HTMLElement) {
+		if article := web_collector.Get(web_collector.requestURL(h.Request)); article != nil {
+			web_collector.readBodyIntoDocument(article, h.Response)
+		}
+	})
+	return web_collector
+}
+
+// rss and atom in one. encoding/xml ignores the name of the root element so <rss> fills Channel and <feed> fills Title and Entries
+type feedXML struct {
+	Title   string `xml:"title"`
+	Channel struct {
+		Title string     `xml:"title"`
+		Items []feedItem `xml:"item"`
+	} `xml:"channel"`
+	Entries []feedItem `xml:"entry"`
+}
+
+// an rss item or an atom entry. encoding/xml gives an element to the first field that matches its name so the
+// namespaced elements have to come before the plain ones, e.g. media:content before content
+type feedItem struct {
+	ItunesDuration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesImage    struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	ItunesSummary  string         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd summary"`
+	MediaContent   []mediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail []mediaURL     `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroup     struct {
+		Content     []mediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnail   []mediaURL     `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+		Description string         `xml:"http://search.yahoo.com/mrss/ description"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+	Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+
+	Title       string         `xml:"title"`
+	Links       []feedLink     `xml:"link"`
+	GUID        string         `xml:"guid"`
+	Description string         `xml:"description"`
+	Summary     string         `xml:"summary"`
+	Content     string         `xml:"content"`
+	PubDate     string         `xml:"pubDate"`
+	Published   string         `xml:"published"`
+	Updated     string         `xml:"updated"`
+	Authors     []feedAuthor   `xml:"author"`
+	Categories  []feedCategory `xml:"category"`
+	Enclosure   *struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+}
+
+// rss has the link as text and atom in the href of one or more links
+type feedLink struct {
+	Text  string `xml:",chardata"`
+	Href  string `xml:"href,attr"`
+	Rel   string `xml:"rel,attr"`
+	Type  string `xml:"type,attr"`
+	Title string `xml:"title,attr"`
+}
+
+// rss has the author as text and atom as a name element
+type feedAuthor struct {
+	Text string `xml:",chardata"`
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+	// arXiv only
+	Affiliations []string `xml:"http://arxiv.org/schemas/atom affiliation"`
+}
+
+// rss has the category as text and atom in the term attribute
+type feedCategory struct {
+	Text string `xml:",chardata"`
+	Term string `xml:"term,attr"`
+}
+
+type mediaContent struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Medium   string `xml:"medium,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+type mediaURL struct {
+	URL string `xml:"url,attr"`
+}
+
+func (feed feedXML) source(feed_url *url.URL) string {
+	if title := strings.TrimSpace(feed.Channel.Title + feed.Title); title != "" {
+		return title
+	}
+	return feed_url.Host
+}
+
+func (item feedItem) published() string {
+	for _, date := range []string{item.PubDate, item.Published, item.Updated} {
+		if date = strings.TrimSpace(date); date != "" {
+			return date
+		}
+	}
+	return ""
+}
+
+func (item feedItem) toDocument(source string) *Document {
+	article := &Document{
+		URL:    item.link(),
+		Title:  strings.TrimSpace(item.Title),
+		Source: source,
+		Kind:   ARTICLE,
+	}
+	article.SetAuthors(item.authors()...)
+	for _, category := range item.Categories {
+		if keyword := strings.TrimSpace(firstNonEmpty(category.Term, category.Text)); keyword != "" {
+			article.Keywords = append(article.Keywords, keyword)
+		}
+	}
+	if media := item.media(); media != nil {
+		article.Kind = mediaKind(media.MimeType, media.URL)
+		if article.Kind == "" && isVideoPage(article.URL) {
+			article.Kind = VIDEO
+		}
+		// blogs and news sites attach the lead picture of a post as an enclosure or media:content
+		if article.Kind == IMAGE && article.URL != "" && mediaKind("", article.URL) == "" {
+			article.Kind = ARTICLE
+			article.Media = &Media{Thumbnail: firstNonEmpty(media.URL, media.Thumbnail)}
+			return article
+		}
+		if article.Kind != "" {
+			article.Media = media
+			article.Text = media.Description
+			if article.URL == "" {
+				article.URL = media.URL
+			}
+			return article
+		}
+		article.Kind = ARTICLE
+	}
+	return article
+}
+
+// every atom author, else dc:creator, else the rss authors. atom names are taken as they are, the rest are free text
+func (item feedItem) authors() []Author {
+	authors := make([]Author, 0, len(item.Authors))
+	for _, author := range item.Authors {
+		if author.Name != "" {
+			authors = append(authors, Author{Name: author.Name, URL: author.URI, Org: firstNonEmpty(author.Affiliations...)})
+		}
+	}
+	if len(authors) > 0 {
+		return authors
+	}
+	// rss authors are usually e-mail addresses so dc:creator is better
+	if item.Creator != "" {
+		return ParseAuthors(item.Creator)
+	}
+	for _, author := range item.Authors {
+		authors = append(authors, ParseAuthors(author.Text)...)
+	}
+	return authors
+}
+
+func (item feedItem) link() string {
+	for _, link := range item.Links {
+		if text := strings.TrimSpace(link.Text); text != "" {
+			return text
+		}
+		if link.Href != "" && (link.Rel == "" || link.Rel == "alternate") {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	if strings.HasPrefix(item.GUID, "http") {
+		return strings.TrimSpace(item.GUID)
+	}
+	return ""
+}
+
+// atom's <link rel="enclosure">. nil if there is none
+func (item feedItem) enclosureLink() *feedLink {
+	for i := range item.Links {
+		if item.Links[i].Rel == "enclosure" && item.Links[i].Href != "" {
+			return &item.Links[i]
+		}
+	}
+	return nil
+}
+
+// the rss or atom enclosure of the item. nil if there is none
+func (item feedItem) media() *Media {
+	media := &Media{
+		Thumbnail:   item.ItunesImage.Href,
+		Description: htmlToText(firstNonEmpty(item.MediaGroup.Description, item.ItunesSummary, item.Description, item.Summary, item.Content)),
+		Duration:    parseDuration(item.ItunesDuration),
+	}
+	contents := append(item.MediaContent, item.MediaGroup.Content...)
+	switch {
+	case item.Enclosure != nil && item.Enclosure.URL != "":
+		media.URL, media.MimeType = item.Enclosure.URL, item.Enclosure.Type
+	case item.enclosureLink() != nil:
+		link := item.enclosureLink()
+		media.URL, media.MimeType = strings.TrimSpace(link.Href), link.Type
+	case len(contents) > 0:
+		media.URL, media.MimeType = contents[0].URL, contents[0].Type
+		if media.MimeType == "" && contents[0].Medium != "" {
+			// medium is one of image, audio, video, document or executable
+			media.MimeType = contents[0].Medium + "/*"
+		}
+		if media.Duration == 0 {
+			media.Duration = parseDuration(contents[0].Duration)
+		}
+	default:
+		return nil
+	}
+	if thumbnails := append(item.MediaThumbnail, item.MediaGroup.Thumbnail...); media.Thumbnail == "" && len(thumbnails) > 0 {
+		media.Thumbnail = thumbnails[0].URL
+	}
+	if media.MimeType == "" {
+		media.MimeType = mime.TypeByExtension(path.Ext(media.URL))
+	}
+	return media
+}
+
+// //	MEDIA UTILITIES		////
+// PODCAST, VIDEO or IMAGE from the mime type or, failing that, the extension of the URL. "" for anything else
+func mediaKind(mime_type string, media_url string) string {
+	if mime_type == "" {
+		if parsed_url, err := url.Parse(media_url); err == nil {
+			mime_type = mime.TypeByExtension(strings.ToLower(path.Ext(parsed_url.Path)))
+		}
+	}
+	switch strings.SplitN(mime_type, "/", 2)[0] {
+	case "audio":
+		return PODCAST
+	case "video":
+		return VIDEO
+	case "image":
+		return IMAGE
+	}
+	return ""
+}
+
+// youtube style links where the page is a player
+func isVideoPage(page_url string) bool {
+	parsed_url, err := url.Parse(page_url)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(parsed_url.Hostname(), "www.")
+	return (host == "youtube.com" && (parsed_url.Path == "/watch" || strings.HasPrefix(parsed_url.Path, "/shorts/"))) ||
+		host == "youtu.be" || host == "vimeo.com"
+}
+
+// turns links straight to media files into media documents. those are never visited since MEDIA_FILTER keeps them out
+func describeMediaLink(article *Document) {
+	if article.Kind != ARTICLE || article.Media != nil {
+		return
+	}
+	if kind := mediaKind("", article.URL); kind != "" {
+		article.Kind = kind
+		article.Media = &Media{URL: article.URL, MimeType: mime.TypeByExtension(strings.ToLower(path.Ext(article.URL)))}
+	}
+}
+
+// video metadata from the open graph tags of a player page. nil if the page is not a video
+func readVideoMetadata(body []byte) *Media {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
+	if err != nil {
+		return nil
+	}
+	meta := func(names ...string) string {
+		for _, name := range names {
+			if value, ok := doc.Find(`meta[property="` + name + `"], meta[name="` + name + `"], meta[itemprop="` + name + `"]`).First().Attr("content"); ok && value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	if !strings.HasPrefix(meta("og:type"), "video") {
+		return nil
+	}
+	return &Media{
+		URL:         meta("og:video:secure_url", "og:video:url", "og:video", "embedUrl"),
+		MimeType:    meta("og:video:type"),
+		Duration:    parseDuration(meta("video:duration", "duration")),
+		Thumbnail:   meta("og:image"),
+		Description: meta("og:description", "description"),
+	}
+}
+
+// seconds from "3600", "1:02:03", "62:03" or ISO 8601 "PT1H2M3S". 0 if it can't be read
+func parseDuration(value string) int64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return int64(seconds)
+	}
+	if strings.HasPrefix(value, "PT") {
+		var total, number int64
+		for _, r := range value[2:] {
+			switch {
+			case r >= '0' && r <= '9':
+				number = number*10 + int64(r-'0')
+			case r == 'H':
+				total, number = total+number*3600, 0
+			case r == 'M':
+				total, number = total+number*60, 0
+			case r == 'S':
+				total, number = total+number, 0
+			default:
+				return 0
+			}
+		}
+		return total
+	}
+	var total int64
+	for _, part := range strings.Split(value, ":") {
+		number, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + number
+	}
+	return total
+}
+
+func htmlToText(value string) string {
+	if !strings.Contains(value, "<") {
+		return strings.TrimSpace(value)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(value))
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
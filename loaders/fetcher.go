@@ -0,0 +1,197 @@
+package loaders
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/soumitsalman/newscollector/metrics"
+)
+
+// abstracts how a URL becomes bytes. everything a WebLoader downloads goes through its Fetcher
+// so swapping it out changes where the loader gets its content from without touching the extraction
+type Fetcher interface {
+	Fetch(req *http.Request) (*http.Response, error)
+}
+
+// //	HTTP FETCHER		////
+// default fetcher that goes over the network
+type HTTPFetcher struct {
+	Transport http.RoundTripper
+	// added to every request
+	Headers map[string]string
+}
+
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{Transport: http.DefaultTransport}
+}
+
+func (f *HTTPFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	if len(f.Headers) > 0 {
+		// round trippers are not supposed to modify the request they are given
+		req = req.Clone(req.Context())
+		for name, value := range f.Headers {
+			req.Header.Set(name, value)
+		}
+	}
+	return f.Transport.RoundTrip(req)
+}
+
+// //	FIXTURE FETCHER		////
+// serves responses from files in a directory instead of the network. meant for tests.
+// the file for a URL is FixtureName(url) followed by an extension that determines the Content-Type, e.g. hackaday_com_news_sitemap_xml.xml.
+// a .redirect file holds the Location that the URL redirects to. URLs without a fixture get a 404
+type FixtureFetcher struct {
+	Dir string
+}
+
+func NewFixtureFetcher(dir string) *FixtureFetcher {
+	return &FixtureFetcher{Dir: dir}
+}
+
+func (f *FixtureFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	matches, _ := filepath.Glob(filepath.Join(f.Dir, FixtureName(req.URL.String())+".*"))
+	if len(matches) == 0 {
+		return newResponse(req, http.StatusNotFound, "text/plain", nil), nil
+	}
+	body, err := os.ReadFile(matches[0])
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(matches[0]) == _REDIRECT_EXT {
+		resp := newResponse(req, http.StatusMovedPermanently, "text/plain", nil)
+		resp.Header.Set("Location", strings.TrimSpace(string(body)))
+		return resp, nil
+	}
+	content_type := mime.TypeByExtension(filepath.Ext(matches[0]))
+	if content_type == "" {
+		content_type = http.DetectContentType(body)
+	}
+	return newResponse(req, http.StatusOK, content_type, body), nil
+}
+
+// //	RECORDING FETCHER		////
+// passes requests through to another fetcher and saves every successful response and redirect as a fixture that a FixtureFetcher can serve
+type RecordingFetcher struct {
+	Fetcher Fetcher
+	Dir     string
+}
+
+func NewRecordingFetcher(fetcher Fetcher, dir string) *RecordingFetcher {
+	return &RecordingFetcher{Fetcher: fetcher, Dir: dir}
+}
+
+func (f *RecordingFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	resp, err := f.Fetcher.Fetch(req)
+	if err != nil {
+		return resp, err
+	}
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if err := os.MkdirAll(f.Dir, 0755); err != nil {
+			return nil, err
+		}
+		return resp, os.WriteFile(filepath.Join(f.Dir, FixtureName(req.URL.String())+_REDIRECT_EXT), []byte(location), 0644)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := os.MkdirAll(f.Dir, 0755); err != nil {
+		return nil, err
+	}
+	ext := ".bin"
+	if media_type, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		if exts, _ := mime.ExtensionsByType(media_type); len(exts) > 0 {
+			ext = exts[len(exts)-1]
+		}
+	}
+	return resp, os.WriteFile(filepath.Join(f.Dir, FixtureName(req.URL.String())+ext), body, 0644)
+}
+
+// //	FAILING FETCHER		////
+// stands in for a fetcher that could not be set up, e.g. a recording that could not be opened or a proxy that could
+// not be configured, so that the loader fails every request instead of quietly falling back to the network
+type failingFetcher struct {
+	err error
+}
+
+func (f *failingFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+var _FIXTURE_NAME_REGEX = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// extension of the fixtures that hold a redirect
+const _REDIRECT_EXT = ".redirect"
+
+// file name (without extension) that a FixtureFetcher looks up for a URL
+func FixtureName(url string) string {
+	url = strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	return strings.Trim(_FIXTURE_NAME_REGEX.ReplaceAllString(url, "_"), "_")
+}
+
+func newResponse(req *http.Request, status int, content_type string, body []byte) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{content_type}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// lets colly and http.Client go through a Fetcher. also where the fetch latency and download size get measured
+type fetcherTransport struct {
+	fetcher Fetcher
+	// label for logs and metrics
+	source string
+	// set while a document is loaded with a context. colly has no way of passing one to its requests
+	ctx context.Context
+}
+
+func (t *fetcherTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.ctx != nil {
+		req = req.WithContext(t.ctx)
+	}
+	start_time := time.Now()
+	resp, err := t.fetcher.Fetch(req)
+	duration := time.Since(start_time)
+	metrics.FetchLatency.WithLabelValues(t.source).Observe(duration.Seconds())
+	if err != nil {
+		slog.Debug("fetch", "source", t.source, "url", req.URL.String(), "duration", duration, "error", err)
+		return nil, err
+	}
+	slog.Debug("fetch", "source", t.source, "url", req.URL.String(), "status", resp.StatusCode, "duration", duration)
+	resp.Body = &countingBody{ReadCloser: resp.Body, source: t.source}
+	return resp, nil
+}
+
+// adds the bytes read from a response body to the download metric
+type countingBody struct {
+	io.ReadCloser
+	source string
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	metrics.BytesDownloaded.WithLabelValues(body.source).Add(float64(n))
+	return n, err
+}
@@ -0,0 +1,105 @@
+package loaders
+
+import (
+	"encoding/xml"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+const (
+	GITHUB_SOURCE = "GitHub"
+	_GITHUB_BASE  = "https://github.com"
+)
+
+// //	GITHUB RELEASES LOADER		////
+// loads the releases and tags of the repos ("owner/name") published in the last N days from their public Atom feeds,
+// e.g. https://github.com/gocolly/colly/releases.atom. the release notes come with the feed so nothing else is fetched.
+// config.BaseURL can point the loader to somewhere other than https://github.com, e.g. a GitHub Enterprise server
+func NewGitHubReleaseLoader(days int, repos []string, config *WebLoaderConfig) *WebLoader {
+	if config.BaseURL == "" {
+		config.BaseURL = _GITHUB_BASE
+	}
+	if config.Timeout == 0 {
+		config.Timeout = _MAX_TIMEOUT
+	}
+	if config.DisallowedFilters == nil {
+		config.DisallowedFilters = []string{MEDIA_FILTER}
+	}
+	web_collector := internalNewLoader(config)
+	web_collector.collector.AllowURLRevisit = true
+	for _, repo := range repos {
+		repo = strings.Trim(repo, "/")
+		// tags without a release only show up in the tags feed. the ones with a release are already loaded by then
+		web_collector.listings = append(web_collector.listings, config.BaseURL+"/"+repo+"/releases.atom", config.BaseURL+"/"+repo+"/tags.atom")
+	}
+
+	web_collector.collector.OnResponse(func(r *colly.Response) {
+		// renamed repos redirect so the feed is matched by the URL it was requested with. the repo is named after where it ended up
+		if !slices.Contains(web_collector.listings, web_collector.requestURL(r.Request)) {
+			return
+		}
+		var feed feedXML
+		if err := xml.Unmarshal(r.Body, &feed); err != nil {
+			return
+		}
+		repo := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(r.Request.URL.Path, "/"), "/releases.atom"), "/tags.atom")
+		for _, entry := range feed.Entries {
+			date := parseDate(entry.published())
+			article := entry.toRelease(repo)
+			if article.URL == "" || !web_collector.withinDateRange(date, days) || web_collector.inCache(article.URL) {
+				continue
+			}
+			article.PublishDate = date.Unix()
+			web_collector.discover(article)
+		}
+	})
+	return web_collector
+}
+
+func (entry feedItem) toRelease(repo string) *Document {
+	release_url := entry.link()
+	version := releaseVersion(release_url)
+	if version == "" {
+		version = strings.TrimSpace(entry.Title)
+	}
+	title := strings.TrimSpace(entry.Title)
+	// release names are often just the version which says nothing without the repo
+	if !strings.Contains(strings.ToLower(title), strings.ToLower(path.Base(repo))) {
+		title = strings.TrimSpace(repo + " " + title)
+	}
+	text := htmlToText(firstNonEmpty(entry.Content, entry.Summary))
+	// the tags feed repeats the title as the content when there are no notes
+	if text == strings.TrimSpace(entry.Title) {
+		text = ""
+	}
+	article := &Document{
+		URL:      release_url,
+		Title:    title,
+		Text:     text,
+		Source:   GITHUB_SOURCE,
+		Version:  version,
+		Keywords: []string{repo},
+		Quality:  ClassifyQuality(text, nil),
+		Kind:     RELEASE,
+	}
+	article.SetAuthors(entry.authors()...)
+	return article
+}
+
+// the tag in https://github.com/owner/name/releases/tag/v1.2.3
+func releaseVersion(release_url string) string {
+	parsed_url, err := url.Parse(release_url)
+	if err != nil {
+		return ""
+	}
+	if _, tag, ok := strings.Cut(parsed_url.Path, "/releases/tag/"); ok {
+		if tag, err := url.PathUnescape(tag); err == nil {
+			return tag
+		}
+	}
+	return ""
+}
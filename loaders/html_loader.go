@@ -2,8 +2,11 @@ package loaders
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -13,6 +16,7 @@ import (
 	"github.com/gocolly/colly/v2"
 	"github.com/gocolly/colly/v2/extensions"
 	datautils "github.com/soumitsalman/data-utils"
+	"github.com/soumitsalman/newscollector/metrics"
 )
 
 // var (
@@ -25,6 +29,8 @@ import (
 
 const (
 	_MAX_TIMEOUT = 10 * time.Second
+	// label of the loaders that have no name and no site, e.g. a NewDefaultWebTextLoader for single pages
+	_DEFAULT_SOURCE_NAME = "web"
 )
 
 const (
@@ -34,6 +40,11 @@ const (
 
 const (
 	ARTICLE = "article"
+	PODCAST = "podcast"
+	VIDEO   = "video"
+	IMAGE   = "image"
+	RELEASE = "release"
+	PAPER   = "paper"
 )
 
 const (
@@ -42,8 +53,14 @@ const (
 )
 
 const (
-	_YC_HACKERNEWS_SITE = "https://hacker-news.firebaseio.com/v0/topstories.json"
-	_MEDIUM_SITE        = "https://medium.com/sitemap/sitemap.xml"
+	_YC_HACKERNEWS_BASE = "https://hacker-news.firebaseio.com/v0"
+	_MEDIUM_BASE        = "https://medium.com"
+	_YC_HACKERNEWS_USER = "https://news.ycombinator.com/user?id="
+)
+
+const (
+	// URLs of media files that the loaders don't visit
+	MEDIA_FILTER = `(?i)\.(png|jpeg|jpg|gif|webp|mp4|avi|mkv|mp3|wav)$`
 )
 
 // //	GENERIC WEB SITE LOADER		////
@@ -52,18 +69,86 @@ const (
 type WebLoader struct {
 	articles  map[string]*Document
 	collector *colly.Collector
+	// the URL each request was made for by request id. colly replaces the URL of the request with the one it got
+	// redirected to, so the articles are looked up by this instead
+	requested map[uint32]string
+	transport *fetcherTransport
 	Config    *WebLoaderConfig
+	stats     LoaderStats
+	// listings that LoadSite visits besides Config.Sitemap, for loaders that read more than one
+	listings []string
 }
 
 type WebLoaderConfig struct {
+	// name of the source this loader is collecting from. used in logs and as the source label of the metrics.
+	// "" means the host of Sitemap or BaseURL
+	Name              string
 	Sitemap           string
 	DisallowedFilters []string
 	Timeout           time.Duration
 	LocalCache        string
+	// how URLs are turned into bytes. nil means plain HTTP configured by HTTP
+	Fetcher Fetcher
+	// proxy, headers, cookies and TLS settings for the default HTTP fetcher. nil means the defaults
+	HTTP *HTTPConfig
+	// root of the API or site for the specialized loaders (e.g. https://hacker-news.firebaseio.com/v0). "" means the live site
+	BaseURL string
+	// the days window is counted back from this time instead of now. used for replaying recorded runs
+	ReferenceTime time.Time
+	// css selector for the article body. "" means the loader's default
+	BodySelector string
+	// how many pages of a multi-page article to stitch together. 0 or 1 means only the first page
+	MaxPages int
+	// optional archive of every raw response the loader fetches
+	Archive *WARCArchiver
+	// LIVE_MODE, RECORD_MODE or REPLAY_MODE. recording and replaying happen in RecordDir
+	Mode      int
+	RecordDir string
+}
+
+func (c *WebLoader) withinDateRange(date time.Time, range_days int) bool {
+	now := c.Config.ReferenceTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	// 1 is being added to get past some unknown bug
+	if date.AddDate(0, 0, range_days+1).After(now) {
+		return true
+	}
+	c.stats.FilteredByDate++
+	return false
 }
 
+// only used while discovering entries so a hit also counts towards the cache hit metric
 func (c *WebLoader) inCache(url string) bool {
-	return c.articles[url] != nil
+	if c.articles[url] != nil {
+		c.stats.AlreadyLoaded++
+		metrics.CacheHits.WithLabelValues(c.Config.sourceName()).Inc()
+		return true
+	}
+	return false
+}
+
+// adds a newly found entry from a sitemap, feed or listing
+func (c *WebLoader) discover(article *Document) {
+	describeMediaLink(article)
+	c.articles[article.URL] = article
+	c.stats.Discovered++
+	metrics.Discovered.WithLabelValues(c.Config.sourceName()).Inc()
+}
+
+// label for logs and metrics. without a Name it is the host the loader reads from so that the metrics don't get
+// a label per URL, e.g. every query an arXiv loader makes
+func (config *WebLoaderConfig) sourceName() string {
+	if config.Name != "" {
+		return config.Name
+	}
+	for _, site_url := range []string{config.Sitemap, config.BaseURL} {
+		if parsed_url, err := url.Parse(site_url); err == nil && parsed_url.Host != "" {
+			return parsed_url.Host
+		}
+	}
+	return _DEFAULT_SOURCE_NAME
 }
 
 func (c *WebLoader) Get(url string) *Document {
@@ -80,7 +165,16 @@ func (c *WebLoader) ListAll() []*Document {
 	return articles
 }
 
-// this function will return an instance of an extracted WebArticle if the url contains an HTML body
+// the URL the request was made for, before any redirects
+func (c *WebLoader) requestURL(r *colly.Request) string {
+	if url, ok := c.requested[r.ID]; ok {
+		return url
+	}
+	return r.URL.String()
+}
+
+// this function will return an instance of an extracted WebArticle if the url contains an HTML body.
+// the document is returned under the url even if the page was redirected, with its URL set to where it ended up
 func (c *WebLoader) LoadDocument(url string) *Document {
 	article, ok := c.articles[url]
 	// check the cache
@@ -89,13 +183,33 @@ func (c *WebLoader) LoadDocument(url string) *Document {
 		c.articles[url] = article
 		c.collector.Visit(url)
 		c.collector.Wait()
+		// the html handler replaces the placeholder with the extracted document
+		article = c.articles[url]
 	}
 	return article
 }
 
+// same as LoadDocument except that the requests are cancelled when ctx is done
+func (c *WebLoader) LoadDocumentWithContext(ctx context.Context, url string) *Document {
+	c.transport.ctx = ctx
+	defer func() { c.transport.ctx = nil }()
+	return c.LoadDocument(url)
+}
+
 // this function will load all the documents from a sitemap or rss feed
 func (c *WebLoader) LoadSite() []*Document {
-	c.collector.Visit(c.Config.Sitemap)
+	if c.Config.Mode == RECORD_MODE {
+		// pin the days window so that the replay sees exactly the same one
+		if c.Config.ReferenceTime.IsZero() {
+			c.Config.ReferenceTime = time.Now()
+		}
+		writeRecordingManifest(c.Config)
+	}
+	for _, listing := range append([]string{c.Config.Sitemap}, c.listings...) {
+		if listing != "" {
+			c.collector.Visit(listing)
+		}
+	}
 	c.collector.Wait()
 	return c.ListAll()
 }
@@ -113,20 +227,59 @@ func internalNewLoader(config *WebLoaderConfig) *WebLoader {
 		col.SetRequestTimeout(config.Timeout)
 	}
 	extensions.RandomUserAgent(col)
-
-	return &WebLoader{
+	if config.Fetcher == nil {
+		config.Fetcher = NewHTTPFetcher()
+		if config.HTTP != nil {
+			if fetcher, err := NewHTTPFetcherWithConfig(config.HTTP); err == nil {
+				config.Fetcher = fetcher
+			} else {
+				// going direct would bypass the proxy or the CA the source has to be reached through
+				slog.Error("FAILED configuring HTTP", "source", config.sourceName(), "error", err)
+				config.Fetcher = &failingFetcher{err: err}
+			}
+		}
+	}
+	fetcher := recordingFetcher(config, config.Fetcher)
+	if config.Archive != nil {
+		fetcher = config.Archive.Wrap(fetcher)
+	}
+	transport := &fetcherTransport{fetcher: fetcher, source: config.sourceName()}
+	col.WithTransport(transport)
+	if config.HTTP != nil && len(config.HTTP.Cookies) > 0 {
+		col.SetCookieJar(newCookieJar(config.HTTP, config.Sitemap))
+	}
+	web_loader := &WebLoader{
 		articles:  make(map[string]*Document),
 		collector: col,
+		requested: make(map[uint32]string),
+		transport: transport,
 		Config:    config,
 	}
+	col.OnRequest(func(r *colly.Request) {
+		web_loader.requested[r.ID] = r.URL.String()
+	})
+	// html is read by the html handlers of each loader. anything else that was linked as an article is read here
+	col.OnResponse(func(r *colly.Response) {
+		if article := web_loader.articles[web_loader.requestURL(r.Request)]; article != nil {
+			if content_kind, _ := contentKind(r.Headers.Get("Content-Type"), r.Body); content_kind != _HTML_CONTENT {
+				web_loader.readBodyIntoDocument(article, r)
+			}
+		}
+	})
+	col.OnError(func(r *colly.Response, err error) {
+		web_loader.countError(r.StatusCode, err)
+		metrics.Failed.WithLabelValues(config.sourceName()).Inc()
+		slog.Warn("FAILED fetching", "source", config.sourceName(), "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+	})
+	return web_loader
 }
 
 // sitemap_url can be "" if the collector is not purposed for any specific sitemap scrapping
 func NewDefaultWebTextLoader(config *WebLoaderConfig) *WebLoader {
 	web_collector := internalNewLoader(config)
 	web_collector.collector.OnHTML("html", func(h *colly.HTMLElement) {
-		if raw_article := readArticleFromResponse(h.Response); raw_article != nil {
-			web_collector.articles[raw_article.URL] = raw_article
+		if raw_article := web_collector.readArticleFromResponse(h.Response); raw_article != nil {
+			web_collector.articles[web_collector.requestURL(h.Request)] = raw_article
 		}
 	})
 	return web_collector
@@ -135,14 +288,14 @@ func NewDefaultWebTextLoader(config *WebLoaderConfig) *WebLoader {
 func NewRedditLinkLoader() *WebLoader {
 	web_collector := internalNewLoader(&WebLoaderConfig{
 		DisallowedFilters: []string{
-			`(?i)\.(png|jpeg|jpg|gif|webp|mp4|avi|mkv|mp3|wav|pdf)$`,
+			MEDIA_FILTER,
 			`(\/\/v\.redd\.it)|(\/\/i\.redd\.it)|(\/\/www\.reddit\.com\/gallery)|(\/\/www\.youtube\.com)`,
 		},
 	})
 
 	web_collector.collector.OnHTML("html", func(h *colly.HTMLElement) {
-		if article := readArticleFromResponse(h.Response); article != nil {
-			web_collector.articles[article.URL] = article
+		if article := web_collector.readArticleFromResponse(h.Response); article != nil {
+			web_collector.articles[web_collector.requestURL(h.Request)] = article
 		}
 	})
 	return web_collector
@@ -150,12 +303,21 @@ func NewRedditLinkLoader() *WebLoader {
 
 // Loads articles from https://feeds.feedburner.com/TheHackersNews that have been posted in the last N days
 func NewDefaultNewsSitemapLoader(days int, sitemap_url string) *WebLoader {
-	web_collector := internalNewLoader(&WebLoaderConfig{
-		Sitemap:           sitemap_url,
-		LocalCache:        os.Getenv("CACHE_DIR"),
-		Timeout:           _MAX_TIMEOUT,
-		DisallowedFilters: []string{`(?i)\.(png|jpeg|jpg|gif|webp|mp4|avi|mkv|mp3|wav|pdf)$`},
+	return NewNewsSitemapLoader(days, &WebLoaderConfig{
+		Sitemap:    sitemap_url,
+		LocalCache: os.Getenv("CACHE_DIR"),
 	})
+}
+
+// same as NewDefaultNewsSitemapLoader but with the rest of the config. config.Sitemap is the google news sitemap to load
+func NewNewsSitemapLoader(days int, config *WebLoaderConfig) *WebLoader {
+	if config.Timeout == 0 {
+		config.Timeout = _MAX_TIMEOUT
+	}
+	if config.DisallowedFilters == nil {
+		config.DisallowedFilters = []string{MEDIA_FILTER}
+	}
+	web_collector := internalNewLoader(config)
 	web_collector.collector.AllowURLRevisit = true
 
 	// web_collector.collector.OnResponse(func(r *colly.Response) {
@@ -167,8 +329,8 @@ func NewDefaultNewsSitemapLoader(days int, sitemap_url string) *WebLoader {
 		link := x.ChildText("/loc")
 		date := parseDate(x.ChildText("//news:publication_date"))
 
-		if withinDateRange(date, days) && !web_collector.inCache(link) {
-			web_collector.articles[link] = &Document{
+		if web_collector.withinDateRange(date, days) && !web_collector.inCache(link) {
+			web_collector.discover(&Document{
 				URL:         link,
 				PublishDate: date.Unix(),
 				Title:       x.ChildText("//news:title"),
@@ -178,16 +340,16 @@ func NewDefaultNewsSitemapLoader(days int, sitemap_url string) *WebLoader {
 					return *item != ""
 				}),
 				Kind: ARTICLE,
-			}
+			})
 			// now collect the body
 			x.Request.Visit(link)
 		}
 
 	})
 	// just match the whole HTML for links that are being visited
-	web_collector.collector.OnHTML(BODY_EXPR_SHORT, func(h *colly.HTMLElement) {
-		if article := web_collector.Get(h.Request.URL.String()); article != nil {
-			article.Text = readBodyFromResponse(h.Response)
+	web_collector.collector.OnHTML(bodySelector(config, BODY_EXPR_SHORT), func(h *colly.HTMLElement) {
+		if article := web_collector.Get(web_collector.requestURL(h.Request)); article != nil {
+			web_collector.readBodyIntoDocument(article, h.Response)
 		}
 	})
 
@@ -196,10 +358,21 @@ func NewDefaultNewsSitemapLoader(days int, sitemap_url string) *WebLoader {
 
 // loades medium posts from https://medium.com/sitemap/sitemap.xml that have been modified in the last N days
 func NewMediumSiteLoader(days int) *WebLoader {
-	web_collector := internalNewLoader(&WebLoaderConfig{
-		Sitemap:           _MEDIUM_SITE,
-		DisallowedFilters: []string{`(?i)\.(png|jpeg|jpg|gif|webp|mp4|avi|mkv|mp3|wav|pdf)$`},
-	})
+	return NewMediumSiteLoaderWithConfig(days, &WebLoaderConfig{})
+}
+
+// same as NewMediumSiteLoader. config.BaseURL can point the loader to somewhere other than https://medium.com
+func NewMediumSiteLoaderWithConfig(days int, config *WebLoaderConfig) *WebLoader {
+	if config.BaseURL == "" {
+		config.BaseURL = _MEDIUM_BASE
+	}
+	if config.Sitemap == "" {
+		config.Sitemap = config.BaseURL + "/sitemap/sitemap.xml"
+	}
+	if config.DisallowedFilters == nil {
+		config.DisallowedFilters = []string{MEDIA_FILTER}
+	}
+	web_collector := internalNewLoader(config)
 	web_collector.collector.AllowURLRevisit = true
 
 	date_regex := regexp.MustCompile(`(\d{4}-\d{2}-\d{2})`)
@@ -208,7 +381,7 @@ func NewMediumSiteLoader(days int) *WebLoader {
 		link := x.Text
 		date := parseDate(date_regex.FindString(link))
 		// no interest in anything other than posts
-		if strings.Contains(link, "/posts/") && withinDateRange(date, days) {
+		if strings.Contains(link, "/posts/") && web_collector.withinDateRange(date, days) {
 			// this collects the sitemap for the posts
 			x.Request.Visit(link)
 		}
@@ -219,13 +392,13 @@ func NewMediumSiteLoader(days int) *WebLoader {
 		link := x.ChildText("/loc")
 		date := parseDate(x.ChildText("/lastmod"))
 
-		if withinDateRange(date, days) && !web_collector.inCache(link) {
-			web_collector.articles[link] = &Document{
+		if web_collector.withinDateRange(date, days) && !web_collector.inCache(link) {
+			web_collector.discover(&Document{
 				URL:         link,
 				PublishDate: date.Unix(),
 				Source:      MEDIUM_SOURCE,
 				Kind:        ARTICLE,
-			}
+			})
 			// now collect the body
 			x.Request.Visit(link)
 		}
@@ -234,8 +407,8 @@ func NewMediumSiteLoader(days int) *WebLoader {
 	// this is the actual post. just match the whole stuff within article tag for links that are being visited
 	web_collector.collector.OnHTML("html", func(h *colly.HTMLElement) {
 		// get or create because sometime's the URLs change benignly
-		if article := web_collector.Get(h.Request.URL.String()); article != nil {
-			article.Text = readBodyFromResponse(h.Response)
+		if article := web_collector.Get(web_collector.requestURL(h.Request)); article != nil {
+			web_collector.readBodyIntoDocument(article, h.Response)
 		}
 	})
 
@@ -244,15 +417,27 @@ func NewMediumSiteLoader(days int) *WebLoader {
 
 // loads story links from https://hacker-news.firebaseio.com/v0/topstories.json posted in the last N days
 func NewYCHackerNewsSiteLoader() *WebLoader {
+	return NewYCHackerNewsSiteLoaderWithConfig(&WebLoaderConfig{})
+}
+
+// same as NewYCHackerNewsSiteLoader. config.BaseURL can point the loader to somewhere other than https://hacker-news.firebaseio.com/v0
+func NewYCHackerNewsSiteLoaderWithConfig(config *WebLoaderConfig) *WebLoader {
+	if config.BaseURL == "" {
+		config.BaseURL = _YC_HACKERNEWS_BASE
+	}
 	// https://hacker-news.firebaseio.com/v0/topstories.json
-	web_collector := internalNewLoader(&WebLoaderConfig{
-		Sitemap:           _YC_HACKERNEWS_SITE,
-		DisallowedFilters: []string{`(?i)\.(png|jpeg|jpg|gif|webp|mp4|avi|mkv|mp3|wav|pdf)$`},
-	})
+	if config.Sitemap == "" {
+		config.Sitemap = config.BaseURL + "/topstories.json"
+	}
+	if config.DisallowedFilters == nil {
+		config.DisallowedFilters = []string{MEDIA_FILTER}
+	}
+	web_collector := internalNewLoader(config)
+	item_url_prefix := config.BaseURL + "/item/"
 	web_collector.collector.AllowURLRevisit = true
 
 	web_collector.collector.OnResponse(func(r *colly.Response) {
-		url := r.Request.URL.String()
+		url := web_collector.requestURL(r.Request)
 		// visiting the topstories https://hacker-news.firebaseio.com/v0/topstories.json
 		if url == web_collector.Config.Sitemap {
 			// [ 9129911, 9129199, 9127761, 9128141, 9128264, 9127792, 9129248, 9127092, 9128367, ..., 9038733 ]
@@ -261,10 +446,10 @@ func NewYCHackerNewsSiteLoader() *WebLoader {
 				// decode successful, now visit these items
 				// https://hacker-news.firebaseio.com/v0/item/8863.json
 				datautils.ForEach(ids, func(item *int64) {
-					r.Request.Visit(fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%d.json", *item))
+					r.Request.Visit(fmt.Sprintf("%s%d.json", item_url_prefix, *item))
 				})
 			}
-		} else if match, _ := regexp.MatchString(`https:\/\/hacker-news\.firebaseio\.com\/v0\/item\/\d+\.json`, url); match {
+		} else if strings.HasPrefix(url, item_url_prefix) && strings.HasSuffix(url, ".json") {
 			// visiting the description/metadata of an item in the topstories
 			var item_data struct {
 				Author string  `json:"by"`
@@ -279,25 +464,29 @@ func NewYCHackerNewsSiteLoader() *WebLoader {
 				item_data.Type == "story" && // type has to be story
 				item_data.URL != "" && // it has to be legit URL and not a text
 				!web_collector.inCache(item_data.URL) { // item has NOT been explored already
-				web_collector.articles[item_data.URL] = &Document{
-					URL:         item_data.URL,
-					Title:       item_data.Title,
-					Author:      item_data.Author,
-					PublishDate: item_data.Time,
-					Source:      YC_HACKERNEWS_SOURCE,
-					Comments:    len(item_data.Kids),
-					Likes:       item_data.Score,
-					Kind:        ARTICLE,
+				article := &Document{
+					URL:           item_data.URL,
+					Title:         item_data.Title,
+					Author:        item_data.Author,
+					PublishDate:   item_data.Time,
+					Source:        YC_HACKERNEWS_SOURCE,
+					Comments:      len(item_data.Kids),
+					Likes:         item_data.Score,
+					EngagementURL: url,
+					Kind:          ARTICLE,
 				}
+				// the submitter
+				article.SetAuthors(Author{Name: item_data.Author, URL: _YC_HACKERNEWS_USER + item_data.Author})
+				web_collector.discover(article)
 				// now collect the body
 				r.Request.Visit(item_data.URL)
 			}
 		}
 	})
 
-	web_collector.collector.OnHTML(BODY_EXPR, func(h *colly.HTMLElement) {
-		if article := web_collector.Get(h.Request.URL.String()); article != nil {
-			article.Text = readBodyFromResponse(h.Response)
+	web_collector.collector.OnHTML(bodySelector(config, BODY_EXPR), func(h *colly.HTMLElement) {
+		if article := web_collector.Get(web_collector.requestURL(h.Request)); article != nil {
+			web_collector.readBodyIntoDocument(article, h.Response)
 		}
 	})
 
@@ -305,9 +494,11 @@ func NewYCHackerNewsSiteLoader() *WebLoader {
 }
 
 // //	INTERNAL UTILITY FUNCTIONS		////
-func withinDateRange(date time.Time, range_days int) bool {
-	// 1 is being added to get past some unknown bug
-	return date.AddDate(0, 0, range_days+1).After(time.Now())
+func bodySelector(config *WebLoaderConfig, default_selector string) string {
+	if config.BodySelector != "" {
+		return config.BodySelector
+	}
+	return default_selector
 }
 
 func parseDate(val string) time.Time {
@@ -337,30 +528,78 @@ func parseDate(val string) time.Time {
 	return time.Time{}
 }
 
-func readArticleFromResponse(resp *colly.Response) *Document {
-	if raw_article, err := readability.FromReader(bytes.NewReader(resp.Body), resp.Request.URL); err == nil {
-		return &Document{
-			URL:   resp.Request.URL.String(),
-			Title: raw_article.Title,
-			Text:  raw_article.TextContent,
-			PublishDate: func() int64 {
-				if raw_article.PublishedTime != nil {
-					return raw_article.PublishedTime.Unix()
-				}
-				return 0
-			}(),
-			Source: resp.Request.URL.Host,
-			Kind:   ARTICLE,
-		}
+func (c *WebLoader) readArticleFromResponse(resp *colly.Response) *Document {
+	if article, err := newDocumentFromHTML(resp.Body, resp.Request.URL); err == nil {
+		c.readBodyIntoDocument(article, resp)
+		return article
 	}
 	return nil
 }
 
-func readBodyFromResponse(resp *colly.Response) string {
-	if raw_article, err := readability.FromReader(bytes.NewReader(resp.Body), resp.Request.URL); err == nil {
-		return raw_article.TextContent
+// title, publish date and source from the page. the body is read separately so that it can go through the fallbacks
+func newDocumentFromHTML(body []byte, page_url *url.URL) (*Document, error) {
+	raw_article, err := readability.FromReader(bytes.NewReader(body), page_url)
+	if err != nil {
+		return nil, err
+	}
+	article := &Document{
+		URL:   page_url.String(),
+		Title: raw_article.Title,
+		PublishDate: func() int64 {
+			if raw_article.PublishedTime != nil {
+				return raw_article.PublishedTime.Unix()
+			}
+			return 0
+		}(),
+		Source: page_url.Host,
+		Kind:   ARTICLE,
+	}
+	if authors := readHTMLAuthors(body, page_url); len(authors) > 0 {
+		article.SetAuthors(authors...)
+	} else {
+		article.SetAuthors(ParseAuthors(raw_article.Byline)...)
+	}
+	return article, nil
+}
+
+func (c *WebLoader) readBodyIntoDocument(article *Document, resp *colly.Response) {
+	// the body selectors can match more than once on the same page and the text is the same every time
+	if article.Quality != "" {
+		return
+	}
+	if content_kind, media_type := contentKind(resp.Headers.Get("Content-Type"), resp.Body); content_kind == _HTML_CONTENT {
+		article.Text = readBodyWithFallbacks(resp.Body, resp.Request.URL, c.fetchHTML)
+		// the listings that the loaders discover the articles from rarely say who wrote them
+		if len(article.Authors) == 0 {
+			if authors := readHTMLAuthors(resp.Body, resp.Request.URL); len(authors) > 0 {
+				article.SetAuthors(authors...)
+			}
+		}
+		if c.Config.MaxPages > 1 && article.Text != "" {
+			article.Text = strings.Join(append([]string{article.Text}, c.readNextPages(resp.Body, resp.Request.URL)...), "\n\n")
+		}
+		if media := readVideoMetadata(resp.Body); media != nil {
+			// player pages have little text besides the description
+			article.Kind, article.Media = VIDEO, media
+			if article.Text == "" {
+				article.Text = media.Description
+			}
+		}
+		article.Quality = ClassifyQuality(article.Text, resp.Body)
+	} else {
+		readNonHTMLIntoDocument(article, content_kind, media_type, resp.Body, resp.Request.URL)
+	}
+	c.stats.Fetched++
+	metrics.Fetched.WithLabelValues(c.Config.sourceName()).Inc()
+	switch {
+	case article.Text != "":
+		metrics.Extracted.WithLabelValues(c.Config.sourceName()).Inc()
+	case article.SkipReason != "":
+		slog.Info("skipped", "source", c.Config.sourceName(), "url", article.URL, "reason", article.SkipReason)
+	default:
+		c.stats.EmptyBodies++
+		slog.Warn("empty body", "source", c.Config.sourceName(), "url", article.URL, "status", resp.StatusCode)
 	}
-	return ""
 }
 
 // func ToPrettyJsonString(data any) string {
@@ -0,0 +1,70 @@
+package loaders
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadDocumentWithContextCancelsFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start_time := time.Now()
+	doc := NewDefaultWebTextLoader(&WebLoaderConfig{Timeout: 10 * time.Second}).LoadDocumentWithContext(ctx, server.URL+"/slow")
+	if duration := time.Since(start_time); duration > 2*time.Second {
+		t.Errorf("expected the fetch to stop with the context, took %s", duration)
+	}
+	if doc == nil || doc.Quality != "" {
+		t.Errorf("expected an unloaded placeholder, got %+v", doc)
+	}
+}
+
+func TestSourceNameIsStable(t *testing.T) {
+	tests := []struct {
+		config *WebLoaderConfig
+		want   string
+	}{
+		{&WebLoaderConfig{Name: "hackaday", Sitemap: "https://hackaday.com/news-sitemap.xml"}, "hackaday"},
+		{&WebLoaderConfig{Sitemap: "https://export.arxiv.org/api/query?search_query=cat:cs.AI&start=0"}, "export.arxiv.org"},
+		{&WebLoaderConfig{BaseURL: "https://github.com"}, "github.com"},
+		{&WebLoaderConfig{}, _DEFAULT_SOURCE_NAME},
+	}
+	for _, test := range tests {
+		if name := test.config.sourceName(); name != test.want {
+			t.Errorf("expected %q for %+v, got %q", test.want, test.config, name)
+		}
+	}
+}
+
+func TestFactoriesDefaultTimeoutAndFilters(t *testing.T) {
+	factories := map[string]func(config *WebLoaderConfig) *WebLoader{
+		"sitemap": func(config *WebLoaderConfig) *WebLoader { return NewNewsSitemapLoader(1, config) },
+		"feed":    func(config *WebLoaderConfig) *WebLoader { return NewFeedLoader(1, config) },
+		"json": func(config *WebLoaderConfig) *WebLoader {
+			mapping := JSON_PRESETS["lobsters"]
+			return NewJSONAggregatorLoader(1, &mapping, config)
+		},
+		"github": func(config *WebLoaderConfig) *WebLoader {
+			return NewGitHubReleaseLoader(1, []string{"gocolly/colly"}, config)
+		},
+	}
+	for name, factory := range factories {
+		loader := factory(&WebLoaderConfig{Sitemap: "https://news.example.com/feed.xml"})
+		if loader.Config.Timeout != _MAX_TIMEOUT {
+			t.Errorf("%s: expected the %s default timeout, got %s", name, _MAX_TIMEOUT, loader.Config.Timeout)
+		}
+		if len(loader.Config.DisallowedFilters) != 1 || loader.Config.DisallowedFilters[0] != MEDIA_FILTER {
+			t.Errorf("%s: expected the media filter, got %v", name, loader.Config.DisallowedFilters)
+		}
+	}
+}
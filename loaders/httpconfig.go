@@ -0,0 +1,87 @@
+package loaders
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// per source HTTP settings. only apply to the default HTTP fetcher
+type HTTPConfig struct {
+	// http://, https:// or socks5:// proxy URL
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// sent with every request
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// preloaded in the cookie jar for the domain of the sitemap, e.g. consent cookies
+	Cookies map[string]string `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	// PEM file with root CAs to trust in addition to the system ones
+	CABundle     string `json:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+	DisableHTTP2 bool   `json:"disable_http2,omitempty" yaml:"disable_http2,omitempty"`
+}
+
+// creates a HTTP fetcher with its own transport built from the config
+func NewHTTPFetcherWithConfig(config *HTTPConfig) (*HTTPFetcher, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.Proxy != "" {
+		proxy_url, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s: %w", config.Proxy, err)
+		}
+		// net/http takes care of socks5 proxies as well
+		transport.Proxy = http.ProxyURL(proxy_url)
+	}
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CA bundle %s: %w", config.CABundle, err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid CA bundle %s: no certificates found", config.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	if config.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		// a non-nil empty map is what turns HTTP/2 off
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return &HTTPFetcher{Transport: transport, Headers: config.Headers}, nil
+}
+
+// cookie jar preloaded with the configured cookies for the domain of site_url
+func newCookieJar(config *HTTPConfig, site_url string) http.CookieJar {
+	jar, _ := cookiejar.New(nil)
+	if parsed_url, err := url.Parse(site_url); err == nil && len(config.Cookies) > 0 {
+		cookies := make([]*http.Cookie, 0, len(config.Cookies))
+		for name, value := range config.Cookies {
+			cookies = append(cookies, &http.Cookie{
+				Name:   name,
+				Value:  value,
+				Path:   "/",
+				Domain: strings.TrimPrefix(parsed_url.Hostname(), "www."),
+			})
+		}
+		jar.SetCookies(parsed_url, cookies)
+	}
+	return jar
+}
+
+// parses "name=value; name2=value2" pairs. used for headers and cookies in the sources file
+func ParseKeyValuePairs(pairs string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(pairs, ";") {
+		if key, value, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(key) != "" {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
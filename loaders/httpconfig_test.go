@@ -0,0 +1,29 @@
+package loaders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInvalidHTTPConfigFailsTheLoader(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`))
+	}))
+	defer server.Close()
+
+	loader := NewNewsSitemapLoader(2, &WebLoaderConfig{
+		Sitemap: server.URL + "/news-sitemap.xml",
+		HTTP:    &HTTPConfig{CABundle: "testdata/no-such-bundle.pem"},
+	})
+	loader.LoadSite()
+	if hits != 0 {
+		t.Errorf("expected no request to go out without the configured CA bundle, got %d", hits)
+	}
+	if loader.Stats().Errors[ERROR_OTHER] != 1 {
+		t.Errorf("expected the sitemap request to fail, got %v", loader.Stats().Errors)
+	}
+}
@@ -0,0 +1,317 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// where the fields of a story are in the JSON of a link aggregator. paths are JSONPath-style: keys separated by dots,
+// [N] for array elements and "$" (or "") for the root, e.g. "data.children" or "$.hits[0].url"
+type JSONMapping struct {
+	// the array of stories. "" means the document itself is the array
+	Items string `json:"items,omitempty" yaml:"items,omitempty"`
+	// the rest are relative to each story. only URL is required
+	URL      string `json:"url" yaml:"url"`
+	Title    string `json:"title,omitempty" yaml:"title,omitempty"`
+	Author   string `json:"author,omitempty" yaml:"author,omitempty"`
+	Score    string `json:"score,omitempty" yaml:"score,omitempty"`
+	Comments string `json:"comments,omitempty" yaml:"comments,omitempty"`
+	// unix seconds (or milliseconds) or a date string
+	Time string `json:"time,omitempty" yaml:"time,omitempty"`
+	// a string or an array of strings
+	Keywords string `json:"keywords,omitempty" yaml:"keywords,omitempty"`
+	// the JSON with the stories when the loader is not given one
+	Listing string `json:"listing,omitempty" yaml:"listing,omitempty"`
+	// Document.Source of the stories. "" means the host of the listing
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// Document.EngagementURL is EngagementURL with the value at EngagementId in place of %s, e.g. "https://www.reddit.com%s.json"
+	// with "data.permalink". the comments and likes are only refreshed for sites that FetchEngagement can read
+	EngagementId  string `json:"engagement_id,omitempty" yaml:"engagement_id,omitempty"`
+	EngagementURL string `json:"engagement_url,omitempty" yaml:"engagement_url,omitempty"`
+}
+
+// mappings for the aggregators that are known to work
+var JSON_PRESETS = map[string]JSONMapping{
+	"lobsters": {
+		URL:      "url",
+		Title:    "title",
+		Author:   "submitter_user",
+		Score:    "score",
+		Comments: "comment_count",
+		Time:     "created_at",
+		Keywords: "tags",
+		Listing:  "https://lobste.rs/hottest.json",
+		Source:   "Lobsters",
+		// the story JSON
+		EngagementId:  "short_id_url",
+		EngagementURL: "%s.json",
+	},
+	"reddit": {
+		Items:    "data.children",
+		URL:      "data.url",
+		Title:    "data.title",
+		Author:   "data.author",
+		Score:    "data.score",
+		Comments: "data.num_comments",
+		Time:     "data.created_utc",
+		Keywords: "data.link_flair_text",
+		Listing:  "https://www.reddit.com/r/programming/hot.json",
+		Source:   "Reddit",
+		// the .json of the post
+		EngagementId:  "data.permalink",
+		EngagementURL: _REDDIT_BASE + "%s.json",
+	},
+	"hn_algolia": {
+		Items:    "hits",
+		URL:      "url",
+		Title:    "title",
+		Author:   "author",
+		Score:    "points",
+		Comments: "num_comments",
+		Time:     "created_at_i",
+		Listing:  "https://hn.algolia.com/api/v1/search?tags=front_page",
+		Source:   YC_HACKERNEWS_SOURCE,
+		// the same item JSON as the hacker news loader
+		EngagementId:  "objectID",
+		EngagementURL: _YC_HACKERNEWS_BASE + "/item/%s.json",
+	},
+}
+
+// //	JSON AGGREGATOR LOADER		////
+// loads the stories posted in the last N days from the JSON listing of a link aggregator (config.Sitemap, or the
+// mapping's Listing) and visits the links for their body. the same as the hacker news loader except that where the
+// fields are comes from the mapping, so new aggregators can be added by configuration
+func NewJSONAggregatorLoader(days int, mapping *JSONMapping, config *WebLoaderConfig) *WebLoader {
+	if config.Sitemap == "" {
+		config.Sitemap = mapping.Listing
+	}
+	if config.Timeout == 0 {
+		config.Timeout = _MAX_TIMEOUT
+	}
+	if config.DisallowedFilters == nil {
+		config.DisallowedFilters = []string{MEDIA_FILTER}
+	}
+	web_collector := internalNewLoader(config)
+	web_collector.collector.AllowURLRevisit = true
+
+	web_collector.collector.OnResponse(func(r *colly.Response) {
+		// the listing may have been redirected so it is matched by the URL it was requested with
+		if web_collector.requestURL(r.Request) != web_collector.Config.Sitemap {
+			return
+		}
+		decoder := json.NewDecoder(bytes.NewReader(r.Body))
+		// keeps large ids and unix milliseconds exact
+		decoder.UseNumber()
+		var listing any
+		if err := decoder.Decode(&listing); err != nil {
+			return
+		}
+		items, _ := jsonPath(listing, mapping.Items).([]any)
+		for _, item := range items {
+			article := mapping.toDocument(item)
+			if article.Source == "" {
+				article.Source = r.Request.URL.Host
+			}
+			// text posts don't link anywhere. stories without a time are kept since their age is unknown
+			if article.URL == "" || (article.PublishDate != 0 && !web_collector.withinDateRange(time.Unix(article.PublishDate, 0), days)) || web_collector.inCache(article.URL) {
+				continue
+			}
+			web_collector.discover(article)
+			// now collect the body
+			r.Request.Visit(article.URL)
+		}
+	})
+	web_collector.collector.OnHTML(bodySelector(config, BODY_EXPR), func(h *colly.HTMLElement) {
+		if article := web_collector.Get(web_collector.requestURL(h.Request)); article != nil {
+			web_collector.readBodyIntoDocument(article, h.Response)
+		}
+	})
+	return web_collector
+}
+
+func (mapping *JSONMapping) toDocument(item any) *Document {
+	article := &Document{
+		URL:      jsonString(jsonPath(item, mapping.URL)),
+		Title:    jsonString(jsonPath(item, mapping.Title)),
+		Likes:    int(jsonNumber(jsonPath(item, mapping.Score))),
+		Comments: int(jsonNumber(jsonPath(item, mapping.Comments))),
+		Source:   mapping.Source,
+		Kind:     ARTICLE,
+	}
+	// 0 when the time is missing or can't be read
+	if publish_date := jsonTime(jsonPath(item, mapping.Time)); !publish_date.IsZero() {
+		article.PublishDate = publish_date.Unix()
+	}
+	article.SetAuthors(jsonAuthors(jsonPath(item, mapping.Author))...)
+	switch keywords := jsonPath(item, mapping.Keywords).(type) {
+	case []any:
+		for _, keyword := range keywords {
+			if keyword := jsonString(keyword); keyword != "" {
+				article.Keywords = append(article.Keywords, keyword)
+			}
+		}
+	case string:
+		if keywords != "" {
+			article.Keywords = []string{keywords}
+		}
+	}
+	if mapping.EngagementId != "" && mapping.EngagementURL != "" {
+		if id := jsonString(jsonPath(item, mapping.EngagementId)); id != "" {
+			article.EngagementURL = fmt.Sprintf(mapping.EngagementURL, id)
+		}
+	}
+	return article
+}
+
+// checks that the paths can be read. the values themselves are only known once the listing is fetched
+func (mapping *JSONMapping) Validate() error {
+	if mapping.URL == "" {
+		return fmt.Errorf("url path is required")
+	}
+	for _, path := range []string{mapping.Items, mapping.URL, mapping.Title, mapping.Author, mapping.Score, mapping.Comments, mapping.Time, mapping.Keywords, mapping.Source, mapping.EngagementId} {
+		if _, err := splitJSONPath(path); err != nil {
+			return err
+		}
+	}
+	if (mapping.EngagementId == "") != (mapping.EngagementURL == "") {
+		return fmt.Errorf("engagement id and engagement url have to be set together")
+	}
+	// the id goes into the one %s and any other verb would come out as %!v(MISSING)
+	if mapping.EngagementURL != "" && (strings.Count(mapping.EngagementURL, "%s") != 1 || strings.Count(strings.ReplaceAll(mapping.EngagementURL, "%%", ""), "%") != 1) {
+		return fmt.Errorf("engagement url %q has to have exactly one %%s for the engagement id", mapping.EngagementURL)
+	}
+	return nil
+}
+
+// //	JSON PATHS		////
+// the value at the path. nil if there is nothing there
+func jsonPath(node any, path string) any {
+	if path == "" && node != nil {
+		return node
+	}
+	steps, err := splitJSONPath(path)
+	if err != nil {
+		return nil
+	}
+	for _, step := range steps {
+		switch val := node.(type) {
+		case map[string]any:
+			node = val[step]
+		case []any:
+			index, err := strconv.Atoi(step)
+			if err != nil || index < 0 || index >= len(val) {
+				return nil
+			}
+			node = val[index]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// "$.data.children[0].url" -> data, children, 0, url
+func splitJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, nil
+	}
+	steps := make([]string, 0)
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key != "" {
+			steps = append(steps, key)
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			if _, err := strconv.Atoi(index); !ok || err != nil {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			steps = append(steps, index)
+			rest = strings.TrimPrefix(after, "[")
+		}
+		if key == "" && !strings.Contains(part, "[") {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return steps, nil
+}
+
+func jsonString(node any) string {
+	switch val := node.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case json.Number:
+		return val.String()
+	case map[string]any:
+		// e.g. {"username": "..."} for users
+		for _, key := range []string{"name", "username", "login"} {
+			if name, ok := val[key].(string); ok {
+				return strings.TrimSpace(name)
+			}
+		}
+	}
+	return ""
+}
+
+// a name, a user object or an array of either
+func jsonAuthors(node any) []Author {
+	if users, ok := node.([]any); ok {
+		authors := make([]Author, 0, len(users))
+		for _, user := range users {
+			authors = append(authors, jsonAuthors(user)...)
+		}
+		return authors
+	}
+	author := Author{Name: jsonString(node)}
+	if user, ok := node.(map[string]any); ok {
+		author.URL = jsonString(firstNonNil(user["url"], user["html_url"]))
+	}
+	return []Author{author}
+}
+
+func firstNonNil(values ...any) any {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+func jsonNumber(node any) float64 {
+	switch val := node.(type) {
+	case json.Number:
+		number, _ := val.Float64()
+		return number
+	case string:
+		number, _ := strconv.ParseFloat(val, 64)
+		return number
+	}
+	return 0
+}
+
+// unix seconds, unix milliseconds or any of the date strings parseDate knows
+func jsonTime(node any) time.Time {
+	switch val := node.(type) {
+	case json.Number:
+		seconds, err := val.Float64()
+		if err != nil || seconds <= 0 {
+			return time.Time{}
+		}
+		// nothing in seconds is this far in the future
+		if seconds > 1e12 {
+			return time.UnixMilli(int64(seconds))
+		}
+		return time.Unix(int64(seconds), 0)
+	case string:
+		return parseDate(strings.TrimSpace(val))
+	}
+	return time.Time{}
+}
@@ -0,0 +1,52 @@
+package loaders
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedditPresetSetsEngagementURL(t *testing.T) {
+	var item any
+	json.Unmarshal([]byte(`{"kind": "t3", "data": {
+		"url": "https://example.com/post",
+		"title": "A post",
+		"permalink": "/r/programming/comments/1d4x2yz/a_post/",
+		"created_utc": 1717236000
+	}}`), &item)
+	mapping := JSON_PRESETS["reddit"]
+	doc := mapping.toDocument(item)
+	if want := "https://www.reddit.com/r/programming/comments/1d4x2yz/a_post/.json"; doc.EngagementURL != want {
+		t.Errorf("expected engagement URL %s, got %q", want, doc.EngagementURL)
+	}
+}
+
+func TestJSONMappingValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping JSONMapping
+		err     string
+	}{
+		{"engagement url", JSONMapping{URL: "link", EngagementId: "id", EngagementURL: "https://links.example.com/items/%s.json"}, ""},
+		{"escaped percent", JSONMapping{URL: "link", EngagementId: "id", EngagementURL: "https://links.example.com/items/%s.json?fields=100%%"}, ""},
+		{"no url", JSONMapping{Title: "headline"}, "url path is required"},
+		{"no placeholder", JSONMapping{URL: "link", EngagementId: "id", EngagementURL: "https://links.example.com/items.json"}, "exactly one %s"},
+		{"two placeholders", JSONMapping{URL: "link", EngagementId: "id", EngagementURL: "https://links.example.com/%s/items/%s.json"}, "exactly one %s"},
+		{"other verb", JSONMapping{URL: "link", EngagementId: "id", EngagementURL: "https://links.example.com/items/%d.json"}, "exactly one %s"},
+		{"url without id", JSONMapping{URL: "link", EngagementURL: "https://links.example.com/items/%s.json"}, "set together"},
+		{"id without url", JSONMapping{URL: "link", EngagementId: "id"}, "set together"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.mapping.Validate()
+			if (test.err == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+	for name, preset := range JSON_PRESETS {
+		if err := preset.Validate(); err != nil {
+			t.Errorf("preset %s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package loaders_test
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+var (
+	update = flag.Bool("update", false, "rewrite the golden files from the recorded fixtures")
+	record = flag.Bool("record", false, "fetch from the live sites and refresh both the fixtures and the golden files")
+)
+
+func TestLoadersAgainstGoldenFiles(t *testing.T) {
+	mode := loadertest.VERIFY
+	switch {
+	case *record:
+		mode = loadertest.RECORD
+	case *update:
+		mode = loadertest.UPDATE_GOLDEN
+	}
+	for _, test_case := range loadertest.Cases {
+		t.Run(test_case.Name, func(t *testing.T) {
+			if mode == loadertest.RECORD && test_case.Synthetic {
+				t.Skip("hand-written fixtures")
+			}
+			if err := loadertest.RunCase("testdata", test_case, mode, serveFixtures); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// serves the recorded fixtures over httptest. requests are looked up by the URL they were originally meant for
+// so the loader gets a fetcher that sends everything to the server regardless of the host
+func serveFixtures(dir string) (loaders.Fetcher, func()) {
+	fixtures := loaders.NewFixtureFetcher(dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the fetcher keeps the original host in the Host header
+		original := *r.URL
+		original.Host = r.Host
+		req := r.Clone(r.Context())
+		req.URL = &original
+		resp, err := fixtures.Fetch(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	server_url, _ := url.Parse(server.URL)
+	return &serverFetcher{server_url: server_url, transport: server.Client().Transport}, server.Close
+}
+
+type serverFetcher struct {
+	server_url *url.URL
+	transport  http.RoundTripper
+}
+
+func (f *serverFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = f.server_url.Scheme
+	redirected.URL.Host = f.server_url.Host
+	redirected.Host = req.URL.Host
+	resp, err := f.transport.RoundTrip(redirected)
+	if err != nil {
+		return nil, err
+	}
+	// colly takes the request of the response as the final URL of the page
+	resp.Request = req
+	return resp, nil
+}
@@ -0,0 +1,76 @@
+package loadertest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// //	TEST SITE		////
+// one article that the unit tests of the loaders, the collector and the server share
+
+const (
+	// path of the article
+	ARTICLE_PATH = "/2024/06/night-buses/"
+	// path that redirects to the article with a 301, like http to https, trailing slash and shortener redirects do
+	REDIRECT_PATH = "/night-buses"
+	// in the text of the article and nowhere else
+	ARTICLE_PHRASE = "northern suburbs"
+)
+
+// html of the article. long enough to be classified as loaders.QUALITY_OK
+func ArticlePage() string {
+	paragraph := "<p>The city council voted on Monday to extend the night bus service to the northern suburbs, " +
+		"a change that residents have asked for since the last train was cut three years ago. " +
+		"The new routes start in September and will run every twenty minutes until two in the morning.</p>"
+	return fmt.Sprintf("<html><head><title>Night buses</title></head><body><article><h1>Night buses</h1>%s</article></body></html>", strings.Repeat(paragraph, 6))
+}
+
+// serves the article at ARTICLE_PATH and a redirect to it at REDIRECT_PATH. close it when done
+func NewRedirectingSite() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case REDIRECT_PATH:
+			http.Redirect(w, r, ARTICLE_PATH, http.StatusMovedPermanently)
+		case ARTICLE_PATH:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, ArticlePage())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// a loaders.Fetcher that serves html pages by URL without the network and remembers every request.
+// URLs that are not in Pages get a 404
+type PagesFetcher struct {
+	Pages    map[string]string
+	Requests []*http.Request
+	lock     sync.Mutex
+}
+
+func (f *PagesFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.Requests = append(f.Requests, req)
+	status, content_type := http.StatusOK, "text/html; charset=utf-8"
+	page, ok := f.Pages[req.URL.String()]
+	if !ok {
+		status, content_type = http.StatusNotFound, "text/plain"
+	}
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{content_type}},
+		Body:          io.NopCloser(bytes.NewReader([]byte(page))),
+		ContentLength: int64(len(page)),
+		Request:       req,
+	}, nil
+}
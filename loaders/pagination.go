@@ -0,0 +1,142 @@
+package loaders
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/extensions"
+)
+
+// //	MULTI-PAGE ARTICLES		////
+// some sites split long stories across ?page=2 or /2/ pages. when WebLoaderConfig.MaxPages is more than 1 the
+// loader follows the next page links up to that many pages and appends the text of each page in order.
+// the next page is taken from, in order
+//  1. <link rel=next> or <a rel=next>
+//  2. the "next" link of the usual pagination widgets
+//  3. a link on the page to the following page number as ?page=N, /page/N/ or /N/
+
+// css selectors of the "next" link of the usual pagination widgets
+const _NEXT_PAGE_EXPR = ".pagination a.next, .pagination .next a, .pager a.next, .pager-next a, .nav-links a.next, a.next-page, a.page-next, a.pagination-next"
+
+// text of the pages that follow the first one. page_url is the URL of the first page
+func (c *WebLoader) readNextPages(body []byte, page_url *url.URL) []string {
+	pages := make([]string, 0)
+	visited := map[string]bool{page_url.String(): true}
+	// some sites send the first page (or the last one) back for page numbers that don't exist
+	seen_texts := map[string]bool{readBodyFromHTML(body, page_url): true}
+	page_number := 1
+	for len(pages)+1 < c.Config.MaxPages {
+		next_url := findNextPageURL(body, page_url, page_number)
+		if next_url == nil || visited[next_url.String()] {
+			break
+		}
+		visited[next_url.String()] = true
+		next_body, final_url := c.fetchHTML(next_url)
+		if next_body == nil {
+			break
+		}
+		text := readBodyFromHTML(next_body, final_url)
+		if strings.TrimSpace(text) == "" || seen_texts[text] {
+			break
+		}
+		seen_texts[text] = true
+		pages = append(pages, text)
+		body, page_url = next_body, final_url
+		page_number++
+	}
+	return pages
+}
+
+func findNextPageURL(body []byte, page_url *url.URL, page_number int) *url.URL {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	for _, selector := range []string{"link[rel=next], a[rel=next]", _NEXT_PAGE_EXPR} {
+		if href, ok := doc.Find(selector).First().Attr("href"); ok {
+			if next_url := sameSiteURL(page_url, href); next_url != nil {
+				return next_url
+			}
+		}
+	}
+	// only follow a guessed page number if the page actually links to it
+	candidates := pageNumberURLs(page_url, page_number+1)
+	var next_url *url.URL
+	doc.Find("a[href]").EachWithBreak(func(_ int, anchor *goquery.Selection) bool {
+		link := sameSiteURL(page_url, anchor.AttrOr("href", ""))
+		if link == nil {
+			return true
+		}
+		for _, candidate := range candidates {
+			if sameURL(link, candidate) {
+				next_url = link
+				return false
+			}
+		}
+		return true
+	})
+	return next_url
+}
+
+// the URL of page N following the ?page=N, /page/N/ and /N/ conventions
+func pageNumberURLs(page_url *url.URL, page_number int) []*url.URL {
+	number := strconv.Itoa(page_number)
+	query_url := *page_url
+	query := query_url.Query()
+	query.Set("page", number)
+	query_url.RawQuery = query.Encode()
+
+	// the path of the first page without the number of the current page
+	base_path := strings.TrimSuffix(page_url.Path, "/")
+	for _, suffix := range []string{fmt.Sprintf("/page/%d", page_number-1), fmt.Sprintf("/%d", page_number-1)} {
+		if page_number > 2 && strings.HasSuffix(base_path, suffix) {
+			base_path = strings.TrimSuffix(base_path, suffix)
+			break
+		}
+	}
+	page_path_url, number_path_url := *page_url, *page_url
+	page_path_url.Path = base_path + "/page/" + number + "/"
+	number_path_url.Path = base_path + "/" + number + "/"
+	return []*url.URL{&query_url, &page_path_url, &number_path_url}
+}
+
+// the link resolved against the page. nil if it leads to another site
+func sameSiteURL(page_url *url.URL, href string) *url.URL {
+	link, err := page_url.Parse(strings.TrimSpace(href))
+	if err != nil || link.Host != page_url.Host || (link.Scheme != "http" && link.Scheme != "https") {
+		return nil
+	}
+	link.Fragment = ""
+	return link
+}
+
+// ignores the trailing slash and the order of the query parameters
+func sameURL(a, b *url.URL) bool {
+	return strings.TrimSuffix(a.Path, "/") == strings.TrimSuffix(b.Path, "/") && a.Query().Encode() == b.Query().Encode()
+}
+
+// body and final URL of an html page. nil if it could not be fetched or is not html.
+// goes through a clone of the loader's collector so that the page is fetched like every other page of the loader:
+// through its fetcher, with a random user agent, its cookies and its rate limits
+func (c *WebLoader) fetchHTML(page_url *url.URL) ([]byte, *url.URL) {
+	var body []byte
+	var final_url *url.URL
+	// the clone shares the transport, cookie jar and limits of the collector but none of its callbacks
+	page_collector := c.collector.Clone()
+	page_collector.AllowURLRevisit = true
+	extensions.RandomUserAgent(page_collector)
+	page_collector.OnResponse(func(r *colly.Response) {
+		if r.StatusCode == http.StatusOK && strings.Contains(r.Headers.Get("Content-Type"), "html") {
+			body, final_url = r.Body, r.Request.URL
+		}
+	})
+	page_collector.Visit(page_url.String())
+	page_collector.Wait()
+	return body, final_url
+}
@@ -0,0 +1,94 @@
+package loaders
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	QUALITY_OK          = "ok"
+	QUALITY_EMPTY       = "empty"
+	QUALITY_TRUNCATED   = "truncated"
+	QUALITY_PAYWALLED   = "paywalled"
+	QUALITY_COOKIE_WALL = "cookie-wall"
+)
+
+const (
+	_MIN_EMPTY_WORDS     = 25
+	_MIN_FULL_WORDS      = 150
+	_MAX_WALL_WORDS      = 400
+	_MIN_TEXT_HTML_RATIO = 0.01
+)
+
+var (
+	_PAYWALL_MARKERS = []string{
+		"subscribe to continue",
+		"subscribe to read",
+		"subscribe now to",
+		"subscribers only",
+		"for subscribers",
+		"already a subscriber",
+		"to continue reading",
+		"continue reading with",
+		"sign in to read",
+		"log in to continue",
+		"create a free account to continue",
+		"become a member to read",
+		"unlock this article",
+		"start your free trial",
+	}
+	_COOKIE_WALL_MARKERS = []string{
+		"we use cookies",
+		"accept all cookies",
+		"accept cookies",
+		"cookie settings",
+		"cookie policy",
+		"manage consent",
+		"manage your privacy",
+		"your privacy choices",
+	}
+	_NOT_FREE_REGEX = regexp.MustCompile(`(?i)"isAccessibleForFree"\s*:\s*"?false"?`)
+	_LD_JSON_REGEX  = regexp.MustCompile(`(?is)<script[^>]+application/ld\+json[^>]*>(.*?)</script>`)
+)
+
+// classifies the extracted text of a page as ok, empty, truncated, paywalled or cookie-wall.
+// html is the raw page and can be nil if only the text is available
+func ClassifyQuality(text string, html []byte) string {
+	lower_text := strings.ToLower(text)
+	word_count := len(strings.Fields(text))
+
+	if word_count < _MIN_EMPTY_WORDS {
+		return QUALITY_EMPTY
+	}
+	// publishers that mark the article as not free in JSON-LD serve a teaser to everyone who is not logged in
+	for _, ld_json := range _LD_JSON_REGEX.FindAllSubmatch(html, -1) {
+		if _NOT_FREE_REGEX.Match(ld_json[1]) {
+			return QUALITY_PAYWALLED
+		}
+	}
+	if word_count < _MAX_WALL_WORDS {
+		if containsAny(lower_text, _PAYWALL_MARKERS) {
+			return QUALITY_PAYWALLED
+		}
+		if containsAny(lower_text, _COOKIE_WALL_MARKERS) && word_count < _MIN_FULL_WORDS {
+			return QUALITY_COOKIE_WALL
+		}
+		// a big page with very little readable text usually means the body was not rendered on the server
+		if len(html) > 0 && float64(len(text))/float64(len(html)) < _MIN_TEXT_HTML_RATIO {
+			return QUALITY_TRUNCATED
+		}
+	}
+	if word_count < _MIN_FULL_WORDS {
+		return QUALITY_TRUNCATED
+	}
+	return QUALITY_OK
+}
+
+func containsAny(text string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
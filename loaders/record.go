@@ -0,0 +1,65 @@
+package loaders
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// what a loader does with WebLoaderConfig.RecordDir
+const (
+	// fetch from the network as usual
+	LIVE_MODE = iota
+	// fetch from the network and keep every HTTP interaction in RecordDir
+	RECORD_MODE
+	// reproduce a recorded run from RecordDir without touching the network
+	REPLAY_MODE
+)
+
+const _RECORDING_MANIFEST = "recording.json"
+
+// //	RECORD & REPLAY		////
+// a recording is a fixtures directory (see RecordingFetcher) of every successful response and redirect of a run together with
+// a manifest of when the run happened, so that replaying it with the same loader produces the same documents.
+// network errors and other statuses are not recorded and come back as 404 in the replay.
+// the directory is self-contained and can be attached to a bug report as is
+type recordingManifest struct {
+	Name          string    `json:"name"`
+	Sitemap       string    `json:"sitemap"`
+	ReferenceTime time.Time `json:"reference_time"`
+}
+
+// the fetcher for the mode
+func recordingFetcher(config *WebLoaderConfig, fetcher Fetcher) Fetcher {
+	switch config.Mode {
+	case RECORD_MODE:
+		return NewRecordingFetcher(fetcher, config.RecordDir)
+	case REPLAY_MODE:
+		if _, err := os.Stat(config.RecordDir); err != nil {
+			slog.Error("FAILED opening recording", "source", config.sourceName(), "error", err)
+			return &failingFetcher{err: err}
+		}
+		// the days window has to be counted from when the run was recorded. only LoadSite writes the manifest
+		if data, err := os.ReadFile(filepath.Join(config.RecordDir, _RECORDING_MANIFEST)); err == nil && config.ReferenceTime.IsZero() {
+			var manifest recordingManifest
+			if json.Unmarshal(data, &manifest) == nil {
+				config.ReferenceTime = manifest.ReferenceTime
+			}
+		}
+		return NewFixtureFetcher(config.RecordDir)
+	}
+	return fetcher
+}
+
+func writeRecordingManifest(config *WebLoaderConfig) {
+	data, _ := json.MarshalIndent(recordingManifest{Name: config.Name, Sitemap: config.Sitemap, ReferenceTime: config.ReferenceTime}, "", "\t")
+	err := os.MkdirAll(config.RecordDir, 0755)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(config.RecordDir, _RECORDING_MANIFEST), data, 0644)
+	}
+	if err != nil {
+		slog.Error("FAILED saving recording manifest", "source", config.sourceName(), "error", err)
+	}
+}
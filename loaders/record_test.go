@@ -0,0 +1,21 @@
+package loaders_test
+
+import (
+	"testing"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+func TestReplayFollowsRecordedRedirects(t *testing.T) {
+	site := loadertest.NewRedirectingSite()
+	url := site.URL + loadertest.REDIRECT_PATH
+	dir := t.TempDir()
+
+	recorded := loaders.NewDefaultWebTextLoader(&loaders.WebLoaderConfig{Mode: loaders.RECORD_MODE, RecordDir: dir}).LoadDocument(url)
+	site.Close()
+	replayed := loaders.NewDefaultWebTextLoader(&loaders.WebLoaderConfig{Mode: loaders.REPLAY_MODE, RecordDir: dir}).LoadDocument(url)
+	if recorded.Quality != loaders.QUALITY_OK || replayed.Text != recorded.Text || replayed.URL != recorded.URL {
+		t.Errorf("expected the replay to match the recording, got %s %q", replayed.URL, replayed.Text)
+	}
+}
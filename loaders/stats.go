@@ -0,0 +1,70 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+)
+
+// classes of errors counted in LoaderStats.Errors
+const (
+	ERROR_TIMEOUT    = "timeout"
+	ERROR_DNS        = "dns"
+	ERROR_CONNECTION = "connection"
+	ERROR_HTTP_4XX   = "http_4xx"
+	ERROR_HTTP_5XX   = "http_5xx"
+	ERROR_OTHER      = "other"
+)
+
+// what a loader has done so far. the same events also go to the prometheus metrics but these are per loader
+type LoaderStats struct {
+	// new entries found in sitemaps, feeds and listings
+	Discovered int `json:"discovered"`
+	// entries (or sitemaps) outside the days window
+	FilteredByDate int `json:"filtered_by_date"`
+	// entries that had already been loaded
+	AlreadyLoaded int `json:"already_loaded"`
+	// article pages downloaded
+	Fetched int `json:"fetched"`
+	// article pages where no text could be extracted
+	EmptyBodies int `json:"empty_bodies"`
+	// failed requests by error class
+	Errors map[string]int `json:"errors,omitempty"`
+}
+
+// returns a copy of the stats
+func (c *WebLoader) Stats() LoaderStats {
+	stats := c.stats
+	stats.Errors = maps.Clone(c.stats.Errors)
+	return stats
+}
+
+func (c *WebLoader) countError(status int, err error) {
+	if c.stats.Errors == nil {
+		c.stats.Errors = make(map[string]int)
+	}
+	c.stats.Errors[ClassifyError(status, err)]++
+}
+
+// one of the ERROR_* classes for a failed request. status is 0 if there was no response
+func ClassifyError(status int, err error) string {
+	var dns_err *net.DNSError
+	var op_err *net.OpError
+	var net_err net.Error
+	switch {
+	case status >= http.StatusInternalServerError:
+		return ERROR_HTTP_5XX
+	case status >= http.StatusBadRequest:
+		return ERROR_HTTP_4XX
+	case errors.As(err, &dns_err):
+		return ERROR_DNS
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &net_err) && net_err.Timeout()):
+		return ERROR_TIMEOUT
+	case errors.As(err, &op_err):
+		return ERROR_CONNECTION
+	default:
+		return ERROR_OTHER
+	}
+}
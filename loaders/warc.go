@@ -0,0 +1,418 @@
+package loaders
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// WARC files are rotated once they get past this size
+	_DEFAULT_MAX_WARC_SIZE = 100 << 20
+	_WARC_INDEX            = "index.cdx"
+	_WARC_TIME_FORMAT      = "20060102150405"
+	_CDX_HEADER            = " CDX N b a m s k r M S V g\n"
+)
+
+// //	WARC ARCHIVER		////
+// keeps every raw response a loader fetches (sitemaps, JSON and article HTML) in gzipped WARC files so that
+// the collection can be re-extracted later. each fetch is written as a request and a response record, each record
+// is its own gzip member, and every response gets a line in the CDX index (index.cdx) of the directory
+type WARCArchiver struct {
+	Dir string
+	// files are named <Prefix>-<timestamp>-<sequence>.warc.gz
+	Prefix string
+	// a new file is started once the current one gets past this many bytes
+	MaxFileSize int64
+
+	file     *os.File
+	filename string
+	offset   int64
+	sequence int
+	// loaders can share an archiver and the scheduler runs them concurrently
+	lock sync.Mutex
+}
+
+func NewWARCArchiver(dir string) *WARCArchiver {
+	return &WARCArchiver{Dir: dir, Prefix: "newscollector", MaxFileSize: _DEFAULT_MAX_WARC_SIZE}
+}
+
+// wraps a fetcher so that everything it fetches is archived
+func (archiver *WARCArchiver) Wrap(fetcher Fetcher) Fetcher {
+	return &archivingFetcher{fetcher: fetcher, archiver: archiver}
+}
+
+// writes the request and response records of a fetch. body is the response body that has already been read
+func (archiver *WARCArchiver) Archive(req *http.Request, resp *http.Response, body []byte, fetch_time time.Time) error {
+	archiver.lock.Lock()
+	defer archiver.lock.Unlock()
+	if err := archiver.rotate(); err != nil {
+		return err
+	}
+
+	request_id, response_id := newRecordId(), newRecordId()
+	date := fetch_time.UTC().Format(time.RFC3339)
+	target := req.URL.String()
+	if _, err := archiver.writeRecord(textproto.MIMEHeader{
+		"Warc-Type":          {"request"},
+		"Warc-Record-Id":     {request_id},
+		"Warc-Date":          {date},
+		"Warc-Target-Uri":    {target},
+		"Warc-Concurrent-To": {response_id},
+		"Content-Type":       {"application/http; msgtype=request"},
+	}, httpRequestBlock(req)); err != nil {
+		return err
+	}
+
+	payload_digest := "sha1:" + digest(body)
+	response_offset := archiver.offset
+	length, err := archiver.writeRecord(textproto.MIMEHeader{
+		"Warc-Type":           {"response"},
+		"Warc-Record-Id":      {response_id},
+		"Warc-Date":           {date},
+		"Warc-Target-Uri":     {target},
+		"Warc-Payload-Digest": {payload_digest},
+		"Content-Type":        {"application/http; msgtype=response"},
+	}, httpResponseBlock(resp, body))
+	if err != nil {
+		return err
+	}
+
+	media_type, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if media_type == "" {
+		media_type = "-"
+	}
+	index_line := strings.Join([]string{
+		surt(req.URL),
+		fetch_time.UTC().Format(_WARC_TIME_FORMAT),
+		target,
+		media_type,
+		strconv.Itoa(resp.StatusCode),
+		strings.TrimPrefix(payload_digest, "sha1:"),
+		"-",
+		"-",
+		strconv.FormatInt(length, 10),
+		strconv.FormatInt(response_offset, 10),
+		archiver.filename,
+	}, " ") + "\n"
+	return archiver.appendIndex(index_line)
+}
+
+// closes the current file. the next Archive starts a new one
+func (archiver *WARCArchiver) Close() error {
+	archiver.lock.Lock()
+	defer archiver.lock.Unlock()
+	if archiver.file == nil {
+		return nil
+	}
+	err := archiver.file.Close()
+	archiver.file = nil
+	return err
+}
+
+// starts a new file if there is none yet or the current one is full
+func (archiver *WARCArchiver) rotate() error {
+	if archiver.file != nil && archiver.offset < archiver.MaxFileSize {
+		return nil
+	}
+	if archiver.file != nil {
+		archiver.file.Close()
+		archiver.file = nil
+	}
+	if err := os.MkdirAll(archiver.Dir, 0755); err != nil {
+		return err
+	}
+	archiver.sequence++
+	archiver.filename = fmt.Sprintf("%s-%s-%05d.warc.gz", archiver.Prefix, time.Now().UTC().Format(_WARC_TIME_FORMAT), archiver.sequence)
+	file, err := os.OpenFile(filepath.Join(archiver.Dir, archiver.filename), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	archiver.file, archiver.offset = file, 0
+	_, err = archiver.writeRecord(textproto.MIMEHeader{
+		"Warc-Type":      {"warcinfo"},
+		"Warc-Record-Id": {newRecordId()},
+		"Warc-Date":      {time.Now().UTC().Format(time.RFC3339)},
+		"Warc-Filename":  {archiver.filename},
+		"Content-Type":   {"application/warc-fields"},
+	}, []byte("software: newscollector\r\nformat: WARC File Format 1.0\r\n"))
+	return err
+}
+
+// writes the record as its own gzip member and returns the compressed length
+func (archiver *WARCArchiver) writeRecord(header textproto.MIMEHeader, block []byte) (int64, error) {
+	var record bytes.Buffer
+	record.WriteString("WARC/1.0\r\n")
+	// WARC-Type goes first by convention
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if name != "Warc-Type" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	fmt.Fprintf(&record, "WARC-Type: %s\r\n", header.Get("Warc-Type"))
+	for _, name := range names {
+		fmt.Fprintf(&record, "%s: %s\r\n", warcHeaderName(name), header.Get(name))
+	}
+	fmt.Fprintf(&record, "Content-Length: %d\r\n\r\n", len(block))
+	record.Write(block)
+	record.WriteString("\r\n\r\n")
+
+	var compressed bytes.Buffer
+	gzip_writer := gzip.NewWriter(&compressed)
+	gzip_writer.Write(record.Bytes())
+	if err := gzip_writer.Close(); err != nil {
+		return 0, err
+	}
+	n, err := archiver.file.Write(compressed.Bytes())
+	archiver.offset += int64(n)
+	return int64(n), err
+}
+
+func (archiver *WARCArchiver) appendIndex(line string) error {
+	path := filepath.Join(archiver.Dir, _WARC_INDEX)
+	_, exists := os.Stat(path)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if exists != nil {
+		file.WriteString(_CDX_HEADER)
+	}
+	_, err = file.WriteString(line)
+	return err
+}
+
+// //	WARC REPLAY		////
+// one response in an archive as listed in its CDX index
+type WARCEntry struct {
+	URL       string
+	Timestamp time.Time
+	MediaType string
+	Status    int
+	Filename  string
+	Offset    int64
+	Length    int64
+}
+
+// reads the CDX index of an archive directory
+func ReadWARCIndex(dir string) ([]WARCEntry, error) {
+	data, err := os.ReadFile(filepath.Join(dir, _WARC_INDEX))
+	if err != nil {
+		return nil, err
+	}
+	var entries []WARCEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 11 {
+			// header or blank line
+			continue
+		}
+		timestamp, _ := time.Parse(_WARC_TIME_FORMAT, fields[1])
+		status, _ := strconv.Atoi(fields[4])
+		length, err1 := strconv.ParseInt(fields[8], 10, 64)
+		offset, err2 := strconv.ParseInt(fields[9], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("bad index line: %s", line)
+		}
+		entries = append(entries, WARCEntry{
+			URL:       fields[2],
+			Timestamp: timestamp,
+			MediaType: fields[3],
+			Status:    status,
+			Filename:  fields[10],
+			Offset:    offset,
+			Length:    length,
+		})
+	}
+	return entries, nil
+}
+
+// reads the archived response of the entry. the body is already read into memory
+func ReadWARCResponse(dir string, entry WARCEntry) (*http.Response, error) {
+	file, err := os.Open(filepath.Join(dir, entry.Filename))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gzip_reader, err := gzip.NewReader(io.NewSectionReader(file, entry.Offset, entry.Length))
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(gzip_reader)
+	if version, err := reader.ReadString('\n'); err != nil || strings.TrimSpace(version) != "WARC/1.0" {
+		return nil, fmt.Errorf("%s@%d is not a WARC record", entry.Filename, entry.Offset)
+	}
+	if _, err := textproto.NewReader(reader).ReadMIMEHeader(); err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// serves responses from a WARC archive instead of the network so that a loader can re-run an archived collection
+// offline. the latest capture of a URL wins and URLs that were not archived get a 404
+type WARCFetcher struct {
+	Dir     string
+	entries map[string]WARCEntry
+}
+
+func NewWARCFetcher(dir string) (*WARCFetcher, error) {
+	entries, err := ReadWARCIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := &WARCFetcher{Dir: dir, entries: make(map[string]WARCEntry)}
+	for _, entry := range entries {
+		if existing, ok := fetcher.entries[entry.URL]; !ok || !entry.Timestamp.Before(existing.Timestamp) {
+			fetcher.entries[entry.URL] = entry
+		}
+	}
+	return fetcher, nil
+}
+
+func (f *WARCFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	entry, ok := f.entries[req.URL.String()]
+	if !ok {
+		return newResponse(req, http.StatusNotFound, "text/plain", nil), nil
+	}
+	resp, err := ReadWARCResponse(f.Dir, entry)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// rebuilds Documents offline from every archived page the loaders read text from: HTML, PDF, plain text and markdown.
+// sitemaps, feeds, JSON listings and media files are skipped
+func ExtractFromArchive(dir string) ([]*Document, error) {
+	fetcher, err := NewWARCFetcher(dir)
+	if err != nil {
+		return nil, err
+	}
+	var docs []*Document
+	for page_url, entry := range fetcher.entries {
+		if entry.Status != http.StatusOK {
+			continue
+		}
+		resp, err := ReadWARCResponse(dir, entry)
+		if err != nil {
+			return nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		content_kind, media_type := contentKind(resp.Header.Get("Content-Type"), body)
+		if content_kind == _SKIPPED_CONTENT {
+			continue
+		}
+		if doc, err := extractContent(body, content_kind, media_type, page_url); err == nil {
+			docs = append(docs, doc)
+		}
+	}
+	slices.SortFunc(docs, func(a, b *Document) int { return strings.Compare(a.URL, b.URL) })
+	return docs, nil
+}
+
+// //	WARC UTILITIES		////
+type archivingFetcher struct {
+	fetcher  Fetcher
+	archiver *WARCArchiver
+}
+
+func (f *archivingFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	fetch_time := time.Now()
+	resp, err := f.fetcher.Fetch(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	// losing the archive of a page is not worth failing the collection over
+	if err := f.archiver.Archive(req, resp, body, fetch_time); err != nil {
+		slog.Warn("FAILED archiving", "url", req.URL.String(), "error", err)
+	}
+	return resp, nil
+}
+
+func httpRequestBlock(req *http.Request) []byte {
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), req.URL.Host)
+	req.Header.Write(&block)
+	block.WriteString("\r\n")
+	return block.Bytes()
+}
+
+// the body is stored decoded so the encoding headers of the original response no longer apply
+func httpResponseBlock(resp *http.Response, body []byte) []byte {
+	header := resp.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	var block bytes.Buffer
+	fmt.Fprintf(&block, "HTTP/1.1 %d %s\r\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+	header.Write(&block)
+	block.WriteString("\r\n")
+	block.Write(body)
+	return block.Bytes()
+}
+
+func newRecordId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	// version 4 uuid
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+func digest(body []byte) string {
+	sum := sha1.Sum(body)
+	return base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// textproto canonicalizes WARC-Record-ID into Warc-Record-Id
+func warcHeaderName(name string) string {
+	name = strings.Replace(name, "Warc-", "WARC-", 1)
+	name = strings.Replace(name, "-Id", "-ID", 1)
+	return strings.Replace(name, "-Uri", "-URI", 1)
+}
+
+// sort-friendly URI reordering used as the CDX key, e.g. https://www.example.com/a?b=1 -> com,example)/a?b=1
+func surt(page_url *url.URL) string {
+	host := strings.TrimPrefix(strings.ToLower(page_url.Hostname()), "www.")
+	parts := strings.Split(host, ".")
+	slices.Reverse(parts)
+	return strings.Join(parts, ",") + ")" + strings.ToLower(page_url.RequestURI())
+}
@@ -0,0 +1,117 @@
+package loaders_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+const (
+	_ARTICLE_URL = "https://citydesk.example.com/2024/06/night-buses/"
+	_NOTES_URL   = "https://citydesk.example.com/2024/06/night-buses/notes.txt"
+	_SITEMAP_URL = "https://citydesk.example.com/news-sitemap.xml"
+)
+
+func archive(t *testing.T, archiver *loaders.WARCArchiver, page_url, content_type, body string) {
+	req, _ := http.NewRequest(http.MethodGet, page_url, nil)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{content_type}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+	if err := archiver.Archive(req, resp, []byte(body), time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWARCRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	archiver := loaders.NewWARCArchiver(dir)
+	archive(t, archiver, _ARTICLE_URL, "text/html; charset=utf-8", loadertest.ArticlePage())
+	archive(t, archiver, _NOTES_URL, "text/plain", "# Night bus notes\n\nThe routes to the "+loadertest.ARTICLE_PHRASE+" start in September.")
+	archive(t, archiver, _SITEMAP_URL, "application/xml", `<urlset><url><loc>`+_ARTICLE_URL+`</loc></url></urlset>`)
+	if err := archiver.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := loaders.ReadWARCIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].URL != _ARTICLE_URL || entries[0].MediaType != "text/html" || entries[0].Status != http.StatusOK {
+		t.Fatalf("expected an index entry per response, got %+v", entries)
+	}
+	resp, err := loaders.ReadWARCResponse(dir, entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != loadertest.ArticlePage() {
+		t.Errorf("expected the archived page back, got %q", body)
+	}
+
+	fetcher, err := loaders.NewWARCFetcher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for page_url, want := range map[string]int{_SITEMAP_URL: http.StatusOK, "https://citydesk.example.com/missing": http.StatusNotFound} {
+		req, _ := http.NewRequest(http.MethodGet, page_url, nil)
+		if resp, err := fetcher.Fetch(req); err != nil || resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %v %v", page_url, want, resp, err)
+		}
+	}
+
+	// the sitemap has no text of its own
+	docs, err := loaders.ExtractFromArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].URL != _ARTICLE_URL || docs[1].URL != _NOTES_URL {
+		t.Fatalf("expected the article and the notes, got %v", docs)
+	}
+	if !strings.Contains(docs[1].Text, loadertest.ARTICLE_PHRASE) || docs[1].Title != "Night bus notes" {
+		t.Errorf("expected the notes read as markdown, got %q: %q", docs[1].Title, docs[1].Text)
+	}
+}
+
+func TestWARCArchiverRotates(t *testing.T) {
+	dir := t.TempDir()
+	archiver := loaders.NewWARCArchiver(dir)
+	// every record is past the limit so every fetch starts a new file
+	archiver.MaxFileSize = 1
+	archiver.Prefix = "citydesk"
+	page_urls := []string{_ARTICLE_URL, _NOTES_URL, _SITEMAP_URL}
+	for _, page_url := range page_urls {
+		archive(t, archiver, page_url, "text/html", loadertest.ArticlePage())
+	}
+	archiver.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "citydesk-*.warc.gz"))
+	if len(files) != len(page_urls) {
+		t.Fatalf("expected %d files, got %v", len(page_urls), files)
+	}
+	entries, err := loaders.ReadWARCIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range entries {
+		if entry.URL != page_urls[i] || filepath.Join(dir, entry.Filename) != files[i] {
+			t.Errorf("expected %s in %s, got %+v", page_urls[i], files[i], entry)
+		}
+		// the offsets are relative to the file the entry is in
+		resp, err := loaders.ReadWARCResponse(dir, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(resp.Body); !bytes.Equal(body, []byte(loadertest.ArticlePage())) {
+			t.Errorf("%s: expected the archived page back", entry.URL)
+		}
+	}
+}
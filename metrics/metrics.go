@@ -0,0 +1,60 @@
+// prometheus metrics for collection runs. everything is labeled by the name of the source
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const _NAMESPACE = "newscollector"
+
+var (
+	// entries found in sitemaps, feeds and listings that are new and within the days window
+	Discovered = newCounter("discovered_total", "Entries discovered in sitemaps, feeds and listings.")
+	// pages whose body was downloaded
+	Fetched = newCounter("fetched_total", "Article pages downloaded.")
+	// pages that came out of extraction with usable text
+	Extracted = newCounter("extracted_total", "Article pages with extracted text.")
+	// requests that failed with a network error or a bad status
+	Failed = newCounter("failed_total", "Failed requests.")
+	// beans handed to the store
+	Stored = newCounter("stored_total", "Beans handed to the store.")
+	// entries skipped because the loader had already collected them
+	CacheHits = newCounter("cache_hits_total", "Entries skipped because they were already collected.")
+	// response body bytes over the wire
+	BytesDownloaded = newCounter("downloaded_bytes_total", "Response body bytes downloaded.")
+	FetchLatency    = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: _NAMESPACE,
+		Name:      "fetch_duration_seconds",
+		Help:      "Time to fetch a URL.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"source"})
+
+	registry = prometheus.NewRegistry()
+)
+
+func init() {
+	registry.MustRegister(Discovered, Fetched, Extracted, Failed, Stored, CacheHits, BytesDownloaded, FetchLatency)
+}
+
+func newCounter(name, help string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: _NAMESPACE,
+		Name:      name,
+		Help:      help,
+	}, []string{"source"})
+}
+
+// http handler for the /metrics endpoint
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// serves /metrics on addr (e.g. ":9090"). blocks like http.ListenAndServe
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	return http.ListenAndServe(addr, mux)
+}
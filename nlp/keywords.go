@@ -0,0 +1,178 @@
+package nlp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// longer candidate phrases are almost always fragments of sentences rather than keywords
+	_MAX_PHRASE_WORDS = 3
+	_MIN_WORD_LENGTH  = 3
+)
+
+// RAKE needs a much longer stopword list than language detection does to break the text into sensible phrases
+var _EXTRA_ENGLISH_STOPWORDS = []string{
+	"a", "about", "above", "across", "actually", "again", "against", "all", "almost", "along", "already", "although", "always", "am", "among", "an", "another", "any", "anyone", "anything", "around", "at",
+	"back", "because", "become", "becomes", "before", "being", "below", "between", "both", "could", "did", "do", "does", "doing", "done", "down", "during", "each", "either", "else", "enough", "even", "ever", "every",
+	"few", "first", "get", "gets", "getting", "go", "goes", "going", "got", "had", "having", "he", "her", "here", "hers", "him", "his", "how", "however", "i", "if", "just", "last", "least", "less", "like", "made", "make",
+	"makes", "many", "may", "me", "might", "most", "much", "must", "my", "new", "next", "no", "nor", "now", "off", "often", "once", "one", "only", "other", "others", "our", "out", "over", "own", "per", "put", "rather",
+	"really", "same", "say", "says", "see", "she", "should", "since", "so", "some", "something", "still", "such", "take", "than", "them", "then", "these", "those", "though", "through", "thus", "too", "two", "under",
+	"until", "up", "upon", "us", "use", "used", "using", "very", "via", "want", "way", "we", "well", "where", "whether", "while", "whom", "whose", "why", "within", "without", "yet", "you", "your", "year", "years",
+	"today", "yesterday", "according", "including", "told", "time", "people", "lot", "things", "thing", "week", "month", "day", "days", "can't", "won't", "it's", "don't", "i'm",
+}
+
+var _PHRASE_BREAK_REGEX = regexp.MustCompile(`[.,;:!?()\[\]{}"“”|/\\\n\t—–]+`)
+
+// extracts up to max_count keyphrases from the text using RAKE (rapid automatic keyword extraction).
+// the text is broken into candidate phrases at stopwords and punctuation and each phrase is scored by how
+// often its words appear together with other words versus on their own
+func ExtractKeywords(text string, max_count int) []string {
+	lang := DetectLanguage(text)
+	if lang == UNKNOWN_LANGUAGE {
+		lang = "en"
+	}
+	is_stopword := func(word string) bool {
+		return IsStopword(lang, word) || (lang == "en" && _extra_english_stopwords[word])
+	}
+
+	// candidate phrases
+	var phrases [][]string
+	for _, fragment := range _PHRASE_BREAK_REGEX.Split(text, -1) {
+		var phrase []string
+		for _, word := range _WORD_REGEX.FindAllString(fragment, -1) {
+			// short acronyms like AI, EU or US would otherwise be dropped as too short or as stopwords
+			is_acronym := isAcronym(word)
+			word = strings.ToLower(word)
+			if !is_acronym && (is_stopword(word) || len([]rune(word)) < _MIN_WORD_LENGTH || isNumber(word)) {
+				phrases = appendPhrase(phrases, phrase)
+				phrase = nil
+			} else {
+				phrase = append(phrase, word)
+			}
+		}
+		phrases = appendPhrase(phrases, phrase)
+	}
+
+	// word scores are degree / frequency
+	frequency, degree := make(map[string]int), make(map[string]int)
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += len(phrase)
+		}
+	}
+	phrase_scores := make(map[string]float64)
+	phrase_counts := make(map[string]int)
+	for _, phrase := range phrases {
+		key := strings.Join(phrase, " ")
+		phrase_counts[key]++
+		if _, ok := phrase_scores[key]; ok {
+			continue
+		}
+		for _, word := range phrase {
+			phrase_scores[key] += float64(degree[word]) / float64(frequency[word])
+		}
+	}
+
+	// phrases that show up only once in a long text are usually noise so prefer the repeated ones if there are enough of them
+	keywords, repeated := make([]string, 0, len(phrase_scores)), make([]string, 0, len(phrase_scores))
+	for key := range phrase_scores {
+		keywords = append(keywords, key)
+		if phrase_counts[key] > 1 {
+			repeated = append(repeated, key)
+		}
+	}
+	if len(repeated) >= max_count && max_count > 0 {
+		keywords = repeated
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if phrase_scores[keywords[i]] != phrase_scores[keywords[j]] {
+			return phrase_scores[keywords[i]] > phrase_scores[keywords[j]]
+		}
+		return keywords[i] < keywords[j]
+	})
+	return NormalizeKeywords(keywords, max_count)
+}
+
+// lower cases, trims and de-duplicates keywords (including plurals) and keeps at most max_count of them.
+// max_count <= 0 means no limit
+func NormalizeKeywords(keywords []string, max_count int) []string {
+	normalized := make([]string, 0, len(keywords))
+	seen := make(map[string]bool)
+	for _, keyword := range keywords {
+		words := Words(keyword)
+		if len(words) == 0 {
+			continue
+		}
+		keyword = strings.Join(words, " ")
+		// only the last word of a phrase is plural: "electric cars" is "electric car"
+		words[len(words)-1] = singular(words[len(words)-1])
+		key := strings.Join(words, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, keyword)
+		if max_count > 0 && len(normalized) == max_count {
+			break
+		}
+	}
+	return normalized
+}
+
+// words ending in s that are not plurals of a word without it
+var _SINGULAR_S_WORDS = map[string]bool{"news": true, "series": true, "species": true, "physics": true, "economics": true, "politics": true, "analysis": true, "crisis": true}
+
+// the singular of a lower case english word for telling plurals apart. words it doesn't know the plural of are left alone
+func singular(word string) string {
+	switch {
+	case _SINGULAR_S_WORDS[word], len(word) <= 3:
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return word
+	}
+	return strings.TrimSuffix(word, "s")
+}
+
+var _extra_english_stopwords = func() map[string]bool {
+	set := make(map[string]bool, len(_EXTRA_ENGLISH_STOPWORDS))
+	for _, word := range _EXTRA_ENGLISH_STOPWORDS {
+		set[word] = true
+	}
+	return set
+}()
+
+func appendPhrase(phrases [][]string, phrase []string) [][]string {
+	if len(phrase) == 0 || len(phrase) > _MAX_PHRASE_WORDS {
+		return phrases
+	}
+	return append(phrases, phrase)
+}
+
+// two or more letters and all of them upper case
+func isAcronym(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+		letters++
+	}
+	return letters >= 2
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
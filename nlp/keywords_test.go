@@ -0,0 +1,59 @@
+package nlp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeKeywords(t *testing.T) {
+	tests := []struct {
+		keywords []string
+		want     []string
+	}{
+		{[]string{"Robots", "robot", " ROBOTS "}, []string{"robots"}},
+		{[]string{"stories", "story"}, []string{"stories"}},
+		{[]string{"boxes", "box"}, []string{"boxes"}},
+		{[]string{"electric cars", "electric car"}, []string{"electric cars"}},
+		// words that end in s without being plurals
+		{[]string{"news", "new"}, []string{"news", "new"}},
+		{[]string{"bus", "bu"}, []string{"bus", "bu"}},
+		{[]string{"class", "clas"}, []string{"class", "clas"}},
+		{[]string{"analysis", "analysi"}, []string{"analysis", "analysi"}},
+		{[]string{"", "  ", "AI"}, []string{"ai"}},
+	}
+	for _, test := range tests {
+		if got := NormalizeKeywords(test.keywords, 0); !slices.Equal(got, test.want) {
+			t.Errorf("NormalizeKeywords(%q) = %q, want %q", test.keywords, got, test.want)
+		}
+	}
+	if got := NormalizeKeywords([]string{"bus", "train", "ferry"}, 2); len(got) != 2 {
+		t.Errorf("expected at most 2 keywords, got %q", got)
+	}
+}
+
+func TestExtractKeywordsKeepsAcronyms(t *testing.T) {
+	text := `The EU agreed on new rules for AI models on Friday. The rules for AI require companies to publish training data.
+The UK said it would not follow the EU rules for AI and would write its own rules instead.`
+	keywords := ExtractKeywords(text, 0)
+	for _, want := range []string{"ai", "eu"} {
+		if !slices.ContainsFunc(keywords, func(keyword string) bool { return slices.Contains(Words(keyword), want) }) {
+			t.Errorf("expected a keyword with %q, got %q", want, keywords)
+		}
+	}
+	// short lower case words are still too short to be keywords
+	if slices.ContainsFunc(keywords, func(keyword string) bool { return slices.Contains(Words(keyword), "on") }) {
+		t.Errorf("expected no keyword with \"on\", got %q", keywords)
+	}
+}
+
+func TestExtractKeywords(t *testing.T) {
+	if keywords := ExtractKeywords(_ARTICLE, 5); len(keywords) != 5 {
+		t.Errorf("expected 5 keywords, got %q", keywords)
+	}
+	keywords := ExtractKeywords(_ARTICLE, 0)
+	for _, want := range []string{"northern suburbs", "fare increase"} {
+		if !slices.Contains(keywords, want) {
+			t.Errorf("expected %q in the keywords, got %q", want, keywords)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package nlp
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	UNKNOWN_LANGUAGE = ""
+	// too few stopwords to tell the language apart
+	_MIN_STOPWORD_HITS = 5
+)
+
+// the most frequent function words of each language. these make up a large share of any running text
+// and overlap little across languages, which is enough to tell them apart without a model
+var _STOPWORDS = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "for", "it", "with", "as", "was", "on", "are", "be", "this", "by", "have", "from", "or", "not", "but", "they", "which", "their", "has", "been", "would", "were", "will", "its", "more", "than", "about", "there", "when", "also", "into", "can", "after", "said", "who", "what"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "se", "del", "las", "por", "un", "para", "con", "una", "su", "al", "lo", "como", "más", "pero", "sus", "le", "ya", "o", "fue", "este", "ha", "sí", "porque", "esta", "son", "entre", "cuando", "muy", "sin", "sobre", "también", "me", "hasta", "hay", "donde"},
+	"fr": {"le", "la", "les", "de", "des", "et", "en", "un", "une", "du", "est", "que", "qui", "dans", "pour", "pas", "au", "sur", "par", "plus", "il", "ne", "se", "ce", "sont", "avec", "aux", "elle", "mais", "nous", "vous", "leur", "été", "cette", "ont", "ou", "comme", "fait", "aussi", "être", "sans", "son"},
+	"de": {"der", "die", "und", "in", "den", "von", "zu", "das", "mit", "sich", "des", "auf", "für", "ist", "im", "dem", "nicht", "ein", "eine", "als", "auch", "es", "an", "werden", "aus", "er", "hat", "dass", "sie", "nach", "wird", "bei", "einer", "um", "am", "sind", "noch", "wie", "einem", "über", "einen", "so"},
+	"pt": {"o", "a", "de", "que", "e", "do", "da", "em", "um", "para", "com", "não", "uma", "os", "no", "se", "na", "por", "mais", "as", "dos", "como", "mas", "foi", "ao", "ele", "das", "tem", "à", "seu", "sua", "ou", "ser", "quando", "muito", "há", "nos", "já", "está", "eu", "também", "pelo"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "in", "del", "non", "una", "della", "si", "le", "con", "da", "sono", "dei", "al", "lo", "come", "ma", "anche", "nel", "più", "alla", "gli", "questo", "ha", "delle", "nella", "era", "essere", "ci", "tra", "se", "suo", "dopo", "stato", "quando", "molto", "loro"},
+	"nl": {"de", "het", "een", "van", "en", "in", "is", "dat", "op", "te", "zijn", "voor", "met", "die", "niet", "aan", "er", "om", "ook", "als", "dan", "maar", "bij", "door", "wordt", "nog", "uit", "naar", "heeft", "kan", "worden", "hij", "deze", "of", "wel", "zo", "meer", "al", "werd", "zij", "over", "tot"},
+}
+
+var (
+	_stopword_sets = func() map[string]map[string]bool {
+		sets := make(map[string]map[string]bool, len(_STOPWORDS))
+		for lang, words := range _STOPWORDS {
+			sets[lang] = make(map[string]bool, len(words))
+			for _, word := range words {
+				sets[lang][word] = true
+			}
+		}
+		return sets
+	}()
+	_WORD_REGEX = regexp.MustCompile(`[\p{L}\p{N}]+(?:['’][\p{L}]+)?`)
+)
+
+// lower cased words of the text
+func Words(text string) []string {
+	return _WORD_REGEX.FindAllString(strings.ToLower(text), -1)
+}
+
+// returns the ISO 639-1 code of the language whose stopwords show up the most in the text.
+// returns UNKNOWN_LANGUAGE if the text is too short or not in one of the supported languages
+func DetectLanguage(text string) string {
+	hits := make(map[string]int, len(_stopword_sets))
+	for _, word := range Words(text) {
+		for lang, stopwords := range _stopword_sets {
+			if stopwords[word] {
+				hits[lang]++
+			}
+		}
+	}
+	best_lang, best_hits := UNKNOWN_LANGUAGE, _MIN_STOPWORD_HITS-1
+	for lang, count := range hits {
+		if count > best_hits || (count == best_hits && lang < best_lang) {
+			best_lang, best_hits = lang, count
+		}
+	}
+	return best_lang
+}
+
+// true if the word is a stopword in the given language
+func IsStopword(lang, word string) bool {
+	return _stopword_sets[lang][strings.ToLower(word)]
+}
@@ -0,0 +1,138 @@
+package nlp
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	_DAMPING_FACTOR   = 0.85
+	_RANK_ITERATIONS  = 30
+	_MIN_SENTENCE_LEN = 4
+	// anything beyond this is usually boilerplate at the bottom of the page and makes the graph needlessly big
+	_MAX_SENTENCES = 200
+)
+
+// a sentence ends at . ! or ? followed by whitespace, or at a line break
+var _SENTENCE_END_REGEX = regexp.MustCompile(`([.!?]["'”’)]?)\s+|\n+`)
+
+// splits the text into sentences
+func Sentences(text string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range _SENTENCE_END_REGEX.FindAllStringSubmatchIndex(text, -1) {
+		// keep the punctuation with the sentence
+		end := loc[1]
+		if loc[2] >= 0 {
+			end = loc[3]
+		}
+		if sentence := strings.TrimSpace(text[start:end]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		start = loc[1]
+	}
+	if sentence := strings.TrimSpace(text[start:]); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// extractive summary of up to max_sentences sentences using TextRank. sentences are nodes of a graph
+// connected by how many content words they share, and the best connected ones are kept in their original order.
+// "" if max_sentences is not positive
+func Summarize(text string, max_sentences int) string {
+	if max_sentences <= 0 {
+		return ""
+	}
+	sentences := Sentences(text)
+	if len(sentences) > _MAX_SENTENCES {
+		sentences = sentences[:_MAX_SENTENCES]
+	}
+	lang := DetectLanguage(text)
+	// content words of each sentence
+	candidates := make([]int, 0, len(sentences))
+	word_sets := make([]map[string]bool, len(sentences))
+	for i, sentence := range sentences {
+		word_sets[i] = make(map[string]bool)
+		for _, word := range Words(sentence) {
+			if !IsStopword(lang, word) && !(lang == "en" && _extra_english_stopwords[word]) {
+				word_sets[i][word] = true
+			}
+		}
+		if len(word_sets[i]) >= _MIN_SENTENCE_LEN {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) <= max_sentences {
+		return strings.Join(pick(sentences, candidates), " ")
+	}
+
+	// similarity graph
+	n := len(candidates)
+	weights := make([][]float64, n)
+	out_weights := make([]float64, n)
+	for a := range candidates {
+		weights[a] = make([]float64, n)
+		for b := range candidates {
+			if a != b {
+				weights[a][b] = similarity(word_sets[candidates[a]], word_sets[candidates[b]])
+				out_weights[a] += weights[a][b]
+			}
+		}
+	}
+
+	// weighted pagerank
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+	for iteration := 0; iteration < _RANK_ITERATIONS; iteration++ {
+		next_scores := make([]float64, n)
+		for a := 0; a < n; a++ {
+			sum := 0.0
+			for b := 0; b < n; b++ {
+				if weights[b][a] > 0 {
+					sum += weights[b][a] / out_weights[b] * scores[b]
+				}
+			}
+			next_scores[a] = (1 - _DAMPING_FACTOR) + _DAMPING_FACTOR*sum
+		}
+		scores = next_scores
+	}
+
+	ranked := make([]int, n)
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return scores[ranked[i]] > scores[ranked[j]] })
+	selected := make([]int, max_sentences)
+	for i := range selected {
+		selected[i] = candidates[ranked[i]]
+	}
+	sort.Ints(selected)
+	return strings.Join(pick(sentences, selected), " ")
+}
+
+// shared words normalized by the sentence lengths as in the TextRank paper
+func similarity(a, b map[string]bool) float64 {
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	return float64(common) / (math.Log(float64(len(a))+1) + math.Log(float64(len(b))+1))
+}
+
+func pick(sentences []string, indexes []int) []string {
+	picked := make([]string, len(indexes))
+	for i, index := range indexes {
+		picked[i] = sentences[index]
+	}
+	return picked
+}
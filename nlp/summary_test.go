@@ -0,0 +1,20 @@
+package nlp
+
+import "testing"
+
+const _ARTICLE = `The city council approved a new budget for public transport on Monday evening.
+The budget adds three new bus routes connecting the northern suburbs with the city centre.
+Council members argued for hours about the cost of the new bus routes and the fare increase.
+Residents of the northern suburbs have asked for better bus connections for many years.
+The fare increase will take effect in January and the new routes will start running in March.`
+
+func TestSummarizeWithoutSentences(t *testing.T) {
+	for _, max_sentences := range []int{0, -1} {
+		if summary := Summarize(_ARTICLE, max_sentences); summary != "" {
+			t.Errorf("Summarize(text, %d) = %q, want empty", max_sentences, summary)
+		}
+	}
+	if summary := Summarize(_ARTICLE, 2); len(Sentences(summary)) != 2 {
+		t.Errorf("Summarize(text, 2) = %q, want 2 sentences", summary)
+	}
+}
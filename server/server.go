@@ -0,0 +1,298 @@
+// HTTP API for extracting articles and triggering collections from other services.
+//
+//	POST /extract            {"url": "..."} or {"html": "...", "url": "optional base url"} -> Document JSON
+//	POST /collect/{source}   starts collecting from a configured source -> 202
+//	GET  /sources            configured sources with the status of their last run
+//
+// every request needs one of the API keys in the X-API-Key header (or as Authorization: Bearer <key>).
+// the server refuses to start without keys unless ServerConfig.AllowNoAuth is set
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soumitsalman/newscollector/collector"
+	"github.com/soumitsalman/newscollector/loaders"
+)
+
+const (
+	_DEFAULT_TIMEOUT        = 30 * time.Second
+	_DEFAULT_MAX_CONCURRENT = 4
+	_MAX_BODY_SIZE          = 10 << 20
+	// Document.URL for raw html that came without a url
+	_BLANK_URL = "http://localhost/"
+)
+
+// returned by ListenAndServe when there are no API keys and AllowNoAuth is not set
+var ErrNoAPIKeys = errors.New("no API keys configured")
+
+type ServerConfig struct {
+	// keys that are accepted in the X-API-Key header
+	APIKeys []string
+	// accept every request when there are no API keys. only for trusted networks
+	AllowNoAuth bool
+	// how long an extract request can take
+	Timeout time.Duration
+	// max number of extractions and collections running at the same time
+	MaxConcurrent int
+	// template for the loader of /extract, e.g. the HTTP settings for a proxy or a CA bundle. nil means the defaults.
+	// the Name is always "api" and the Timeout defaults to Timeout
+	Loader *loaders.WebLoaderConfig
+}
+
+type extractRequest struct {
+	URL  string `json:"url"`
+	HTML string `json:"html"`
+}
+
+// leaves out the HTTP settings of the source since those can have credentials in them
+type sourceStatus struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	URL      string `json:"url,omitempty"`
+	Category string `json:"category,omitempty"`
+	Schedule string `json:"schedule,omitempty"`
+	Enabled  bool   `json:"enabled"`
+	Running  bool   `json:"running"`
+	// the last run started through POST /collect since the server started. runs from a scheduler or the CLI are not in it
+	LastRun *collector.SourceReport `json:"last_run,omitempty"`
+}
+
+// //	API SERVER		////
+type Server struct {
+	collector collector.NewsSiteCollector
+	Config    *ServerConfig
+	// a slot is taken for every extraction and collection in progress
+	slots     chan struct{}
+	running   map[string]bool
+	last_runs map[string]collector.SourceReport
+	lock      sync.Mutex
+}
+
+func NewServer(site_collector collector.NewsSiteCollector, config *ServerConfig) *Server {
+	if config.Timeout <= 0 {
+		config.Timeout = _DEFAULT_TIMEOUT
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = _DEFAULT_MAX_CONCURRENT
+	}
+	if len(config.APIKeys) == 0 && config.AllowNoAuth {
+		slog.Warn("no API keys configured. the server accepts every request")
+	}
+	return &Server{
+		collector: site_collector,
+		Config:    config,
+		slots:     make(chan struct{}, config.MaxConcurrent),
+		running:   make(map[string]bool),
+		last_runs: make(map[string]collector.SourceReport),
+	}
+}
+
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /extract", jsonContent(http.TimeoutHandler(http.HandlerFunc(server.extract), server.Config.Timeout, `{"error":"timed out"}`)))
+	mux.HandleFunc("POST /collect/{source}", server.collect)
+	mux.HandleFunc("GET /sources", server.listSources)
+	return server.authenticate(mux)
+}
+
+// blocks like http.ListenAndServe
+func (server *Server) ListenAndServe(addr string) error {
+	if len(server.Config.APIKeys) == 0 && !server.Config.AllowNoAuth {
+		return ErrNoAPIKeys
+	}
+	http_server := &http.Server{
+		Addr:              addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       server.Config.Timeout,
+		// the timeout handler answers first if an extraction takes too long
+		WriteTimeout: server.Config.Timeout + 5*time.Second,
+	}
+	return http_server.ListenAndServe()
+}
+
+func (server *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(server.Config.APIKeys) > 0 || !server.Config.AllowNoAuth {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			if !server.validKey(key) {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid API key"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (server *Server) validKey(key string) bool {
+	valid := false
+	for _, api_key := range server.Config.APIKeys {
+		// go through every key so the time taken doesn't give away which one was close
+		if subtle.ConstantTimeCompare([]byte(key), []byte(api_key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (server *Server) extract(w http.ResponseWriter, r *http.Request) {
+	var req extractRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, _MAX_BODY_SIZE)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.URL == "" && req.HTML == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url or html is required"))
+		return
+	}
+	if !server.acquire(r.Context()) {
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many requests in progress"))
+		return
+	}
+	defer server.release()
+
+	if req.HTML != "" {
+		if req.URL == "" {
+			req.URL = _BLANK_URL
+		}
+		doc, err := loaders.ExtractFromHTML(strings.NewReader(req.HTML), req.URL)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, doc)
+		return
+	}
+	// the request context is cancelled when the timeout handler gives up, which stops the fetch and frees the slot
+	doc := loaders.NewDefaultWebTextLoader(server.loaderConfig()).LoadDocumentWithContext(r.Context(), req.URL)
+	// nothing gets classified unless the page was loaded
+	if doc == nil || doc.Quality == "" {
+		writeError(w, http.StatusBadGateway, errors.New("could not load "+req.URL))
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// a fresh copy of the template for every request
+func (server *Server) loaderConfig() *loaders.WebLoaderConfig {
+	var config loaders.WebLoaderConfig
+	if server.Config.Loader != nil {
+		config = *server.Config.Loader
+	}
+	config.Name = "api"
+	if config.Timeout <= 0 {
+		config.Timeout = server.Config.Timeout
+	}
+	return &config
+}
+
+func (server *Server) collect(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("source")
+	if !server.isSource(name) {
+		writeError(w, http.StatusNotFound, errors.New("unknown source "+name))
+		return
+	}
+	server.lock.Lock()
+	if server.running[name] {
+		server.lock.Unlock()
+		writeError(w, http.StatusConflict, errors.New(name+" is already being collected"))
+		return
+	}
+	server.running[name] = true
+	server.lock.Unlock()
+
+	// collections take much longer than a request so they run in the background
+	go func() {
+		defer func() {
+			server.lock.Lock()
+			delete(server.running, name)
+			server.lock.Unlock()
+		}()
+		server.acquire(context.Background())
+		defer server.release()
+		report, err := server.collector.CollectSource(context.Background(), name)
+		if err != nil {
+			slog.Error("FAILED collecting", "source", name, "error", err)
+			return
+		}
+		server.lock.Lock()
+		server.last_runs[name] = report
+		server.lock.Unlock()
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]string{"source": name, "status": "started"})
+}
+
+func (server *Server) listSources(w http.ResponseWriter, r *http.Request) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	sources := server.collector.Sources()
+	statuses := make([]sourceStatus, len(sources))
+	for i, source := range sources {
+		statuses[i] = sourceStatus{
+			Name:     source.Name,
+			Type:     source.Type,
+			URL:      source.URL,
+			Category: source.Category,
+			Schedule: source.Schedule,
+			Enabled:  source.IsEnabled(),
+			Running:  server.running[source.Name],
+		}
+		if report, ok := server.last_runs[source.Name]; ok {
+			statuses[i].LastRun = &report
+		}
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (server *Server) isSource(name string) bool {
+	for _, source := range server.collector.Sources() {
+		if source.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// waits for a free slot. false if ctx is done first
+func (server *Server) acquire(ctx context.Context) bool {
+	select {
+	case server.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (server *Server) release() {
+	<-server.slots
+}
+
+// the error responses of http.TimeoutHandler go out with the headers set here
+func jsonContent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soumitsalman/newscollector/collector"
+	"github.com/soumitsalman/newscollector/loaders"
+	"github.com/soumitsalman/newscollector/loaders/loadertest"
+)
+
+func extract(api_server *Server, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/extract", strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	api_server.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestExtractFollowsRedirects(t *testing.T) {
+	site := loadertest.NewRedirectingSite()
+	defer site.Close()
+
+	api_server := NewServer(collector.NewsSiteCollector{}, &ServerConfig{AllowNoAuth: true})
+	if w := extract(api_server, `{"url": "`+site.URL+loadertest.REDIRECT_PATH+`"}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServerRequiresAPIKeys(t *testing.T) {
+	api_server := NewServer(collector.NewsSiteCollector{}, &ServerConfig{})
+	if err := api_server.ListenAndServe("127.0.0.1:0"); !errors.Is(err, ErrNoAPIKeys) {
+		t.Errorf("expected the server to refuse to start without API keys, got %v", err)
+	}
+	// and to turn every request down if it is used as a handler anyway
+	if w := extract(api_server, `{"html": "<p>hello</p>"}`, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without API keys, got %d", w.Code)
+	}
+
+	api_server = NewServer(collector.NewsSiteCollector{}, &ServerConfig{APIKeys: []string{"key1", "key2"}})
+	tests := []struct {
+		headers map[string]string
+		want    int
+	}{
+		{nil, http.StatusUnauthorized},
+		{map[string]string{"X-API-Key": "key3"}, http.StatusUnauthorized},
+		{map[string]string{"X-API-Key": "key2"}, http.StatusOK},
+		{map[string]string{"Authorization": "Bearer key1"}, http.StatusOK},
+	}
+	for _, test := range tests {
+		if w := extract(api_server, `{"html": "<p>hello</p>"}`, test.headers); w.Code != test.want {
+			t.Errorf("%v: expected %d, got %d", test.headers, test.want, w.Code)
+		}
+	}
+}
+
+func TestExtractTimeoutIsJSON(t *testing.T) {
+	slow_site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow_site.Close()
+
+	api_server := NewServer(collector.NewsSiteCollector{}, &ServerConfig{AllowNoAuth: true, Timeout: 50 * time.Millisecond})
+	w := extract(api_server, `{"url": "`+slow_site.URL+`"}`, nil)
+	var body map[string]string
+	if w.Code != http.StatusServiceUnavailable || json.Unmarshal(w.Body.Bytes(), &body) != nil || body["error"] == "" {
+		t.Errorf("expected a JSON error with 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if content_type := w.Header().Get("Content-Type"); content_type != "application/json" {
+		t.Errorf("expected application/json, got %q", content_type)
+	}
+}
+
+func TestExtractUsesTheLoaderTemplate(t *testing.T) {
+	// stands in for the proxy or CA settings the template would carry
+	fetcher := &loadertest.PagesFetcher{Pages: map[string]string{"https://intranet.example.com/night-buses": loadertest.ArticlePage()}}
+	api_server := NewServer(collector.NewsSiteCollector{}, &ServerConfig{AllowNoAuth: true, Loader: &loaders.WebLoaderConfig{Fetcher: fetcher}})
+	w := extract(api_server, `{"url": "https://intranet.example.com/night-buses"}`, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), loadertest.ARTICLE_PHRASE) {
+		t.Fatalf("expected the article through the template's fetcher, got %d: %s", w.Code, w.Body.String())
+	}
+	if api_server.Config.Loader.Name != "" || api_server.Config.Loader.Timeout != 0 {
+		t.Errorf("expected the template to be left alone, got %+v", api_server.Config.Loader)
+	}
+}